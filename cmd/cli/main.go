@@ -4,10 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/morgansundqvist/muserstory/internal/adapters"
 	"github.com/morgansundqvist/muserstory/internal/application"
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +22,66 @@ type ctxKey string
 
 const svcKey ctxKey = "userStoryService"
 
+var (
+	configPath   string
+	llmProvider  string
+	cassettePath string
+	cassetteMode string
+	noCache      bool
+	promptsDir   string
+	timeout      time.Duration
+	// nonInteractive makes commands fail instead of prompting for input.
+	nonInteractive bool
+	// cancelTimeout releases the --timeout deadline once the command has finished.
+	cancelTimeout context.CancelFunc = func() {}
+	// meter counts the tokens used by the command; it stays nil when no provider is called.
+	meter         *adapters.MeteredLLMService
+	meterProvider string
+	meterConfig   domain.Config
+)
+
+// newLLMService creates the LLM service selected by --llm-provider, MUSERSTORY_LLM_PROVIDER
+// or the config file, in that order. With --cassette the service records to, or is replaced
+// by a replay of, the cassette file. Responses are cached on disk unless --no-cache is set.
+func newLLMService() (ports.LLMService, error) {
+	var mode adapters.CassetteMode
+	if cassettePath != "" {
+		var err error
+		if mode, err = adapters.ParseCassetteMode(cassetteMode); err != nil {
+			return nil, err
+		}
+		if mode == adapters.CassetteReplay {
+			return adapters.NewReplayingLLMService(cassettePath)
+		}
+	}
+
+	config, err := adapters.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	provider := adapters.ResolveLLMProvider(llmProvider, config.LLM)
+	service, err := adapters.NewLLMService(provider, config.LLM)
+	if err != nil {
+		return nil, err
+	}
+	meter, meterProvider, meterConfig = adapters.NewMeteredLLMService(service), provider, config
+	service = meter
+	if !noCache && !config.Cache.Disabled && provider != "offline" {
+		cacheDir := config.Cache.Dir
+		if cacheDir == "" {
+			if cacheDir, err = adapters.DefaultCacheDir(); err != nil {
+				return nil, fmt.Errorf("could not determine cache directory: %w", err)
+			}
+		}
+		// Separate directories keep providers from answering with each other's responses.
+		service = adapters.NewCachingLLMService(service, filepath.Join(cacheDir, provider), provider, config.Cache.TTL, config.Cache.MaxEntries)
+	}
+	if mode == adapters.CassetteRecord {
+		return adapters.NewRecordingLLMService(service, cassettePath), nil
+	}
+	return service, nil
+}
+
 func main() {
 	var filePath string
 
@@ -23,21 +90,41 @@ func main() {
 		Short: "Manage user stories with LLM support",
 		Long:  "A CLI tool to categorize, add, list, summarize, and generate user stories using an LLM service.",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat(); err != nil {
+				return err
+			}
 			if filePath == "" {
 				cmd.Println("Error: markdown file path must be provided with --file flag")
 				return fmt.Errorf("missing required flag: --file")
 			}
-			llmAPI := adapters.NewOpenAILLMService()
+			llmAPI, err := newLLMService()
+			if err != nil {
+				return err
+			}
 			fileReader := adapters.NewLocalFileReader()
 			svc := application.NewUserStoryService(llmAPI, filePath, fileReader)
-			existingCtx := cmd.Context()
-			ctx := context.WithValue(existingCtx, svcKey, svc)
-			cmd.SetContext(ctx)
+			overrides, err := loadPromptOverrides()
+			if err != nil {
+				return err
+			}
+			svc.SetPromptOverrides(overrides)
+			svc.SetInteraction(newTerminal())
+			applyTimeout(cmd)
+			cmd.SetContext(context.WithValue(cmd.Context(), svcKey, svc))
 			return nil
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&filePath, "file", "f", "userstories.md", "Path to the markdown file containing user stories.")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the config file (default: ./.muserstory.yaml or the user config directory)")
+	rootCmd.PersistentFlags().StringVar(&llmProvider, "llm-provider", "", "LLM provider to use: "+strings.Join(adapters.LLMProviderNames(), ", ")+" (default: openai)")
+	rootCmd.PersistentFlags().StringVar(&cassettePath, "cassette", "", "Record LLM requests to, or replay them from, this cassette file")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not read or write the LLM response cache")
+	rootCmd.PersistentFlags().StringVar(&promptsDir, "prompts-dir", "", "Directory of <prompt>.tmpl files that replace the built-in prompts (default: prompts_dir from the config file)")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Abort the command after this long, e.g. 90s or 5m (default: no limit)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputText, "Output format: text or json; with json, results go to stdout and messages to stderr")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Fail instead of prompting for input, for scripts and CI jobs")
+	rootCmd.PersistentFlags().StringVar(&cassetteMode, "cassette-mode", string(adapters.CassetteReplay), "Cassette mode: record or replay")
 
 	rootCmd.AddCommand(categorizeCmd)
 	rootCmd.AddCommand(addCmd)
@@ -45,12 +132,41 @@ func main() {
 	rootCmd.AddCommand(summarizeCmd)
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(pushCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(dedupeCmd)
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(removeCmd)
+	rootCmd.AddCommand(criteriaCmd)
+	rootCmd.AddCommand(prioritizeCmd)
+	rootCmd.AddCommand(reviewCmd)
+	rootCmd.AddCommand(usageCmd)
+	categoryCmd.AddCommand(categoryListCmd, categoryRenameCmd, categoryMergeCmd)
+	rootCmd.AddCommand(categoryCmd)
+	promptsCmd.AddCommand(promptsListCmd, promptsShowCmd, promptsExportCmd)
+	rootCmd.AddCommand(promptsCmd)
 
 	rootCmd.AddCommand(listRemoteCmd)
 
 	rootCmd.AddCommand(getRemoteCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C cancels the running command, which stops LLM and remote requests and saves
+	// partial progress; a second Ctrl-C exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Errors are reported by reportError, which knows about --output json.
+	rootCmd.SilenceErrors = true
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	cancelTimeout()
+	stop()
+	reportUsage(strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "))
+	if err != nil {
+		reportError(err)
 		os.Exit(1)
 	}
 }
@@ -63,13 +179,21 @@ var categorizeCmd = &cobra.Command{
 			return fmt.Errorf("'categorize' takes no arguments")
 		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			return err
+		}
+		batchSize, err := cmd.Flags().GetInt("batch-size")
+		if err != nil {
+			return err
+		}
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Starting categorization for stories in %s...\n", file)
-		if err := svc.CategorizeAllStories(); err != nil {
+		logf("Starting categorization for stories in %s...\n", file)
+		result, err := svc.CategorizeAllStories(cmd.Context(), application.CategorizeOptions{Concurrency: concurrency, BatchSize: batchSize})
+		if err != nil {
 			return err
 		}
-		fmt.Println("Categorization process complete.")
-		return nil
+		return render(result, func() { printCategorization(result) })
 	},
 }
 
@@ -79,10 +203,26 @@ var addCmd = &cobra.Command{
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		story := strings.Join(args, " ")
+		duplicates, err := duplicateCheckFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		propose, err := cmd.Flags().GetBool("propose")
+		if err != nil {
+			return err
+		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Adding story to %s: \"%s\"\n", file, story)
-		return svc.AddUserStory(story)
+		logf("Adding story to %s: \"%s\"\n", file, story)
+		result, err := svc.AddUserStory(cmd.Context(), story, application.AddStoryOptions{Duplicates: duplicates, Force: force, Propose: propose})
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printAddedStory(result) })
 	},
 }
 
@@ -93,10 +233,18 @@ var listCmd = &cobra.Command{
 		if len(args) != 0 {
 			return fmt.Errorf("'list' takes no arguments")
 		}
+		query, err := storyQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Listing stories from %s...\n", file)
-		return svc.ListUserStories()
+		logf("Listing stories from %s...\n", file)
+		list, err := svc.ListUserStories(cmd.Context(), query)
+		if err != nil {
+			return err
+		}
+		return render(list, func() { printStoryList(list, query) })
 	},
 }
 
@@ -109,14 +257,19 @@ var summarizeCmd = &cobra.Command{
 		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Starting summarization for stories in %s...\n", file)
-		return svc.SummarizeStories()
+		logf("Starting summarization for stories in %s...\n", file)
+		summary, err := svc.SummarizeStories(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return render(summary, func() { printSummary(summary) })
 	},
 }
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate new user stories based on existing ones",
+	Long:  "Generate new user stories based on existing ones. They are added with the Proposed status; accept or decline them with review, or pass --yes to accept them right away.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		n, err := cmd.Flags().GetInt("num")
 		if err != nil {
@@ -125,42 +278,664 @@ var generateCmd = &cobra.Command{
 		if n <= 0 {
 			return fmt.Errorf("number of stories must be positive")
 		}
+		duplicates, err := duplicateCheckFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		acceptAll, err := cmd.Flags().GetBool("accept-all")
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+		opts := application.GenerateOptions{Count: n, Duplicates: duplicates, AcceptAll: yes || acceptAll, DryRun: dryRun}
+		if opts.AcceptAll {
+			if opts.Reviewer, err = cmd.Flags().GetString("reviewer"); err != nil {
+				return err
+			}
+			if opts.Reviewer == "" {
+				if opts.Reviewer, err = defaultReviewer(); err != nil {
+					return err
+				}
+			}
+		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Starting generation of %d new stories for %s...\n", n, file)
-		return svc.GenerateNewStories(n)
+		logf("Starting generation of %d new stories for %s...\n", n, file)
+		result, err := svc.GenerateNewStories(cmd.Context(), opts)
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printGeneratedStories(result, file) })
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [uuid] [state]",
+	Short: "Set the workflow status of a user story",
+	Long:  "Set the workflow status of a user story. The UUID may be shortened to any unique prefix. The default workflow is Proposed, Accepted, In Progress, Done and Declined; a custom one can be declared under 'workflow' in the file metadata.",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		state := strings.Join(args[1:], " ")
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		change, err := svc.SetStoryStatus(cmd.Context(), args[0], state, force)
+		if err != nil {
+			return err
+		}
+		return render(change, func() { printStatusChange(change) })
 	},
 }
 
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export user stories to JSON, CSV or YAML",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("'export' takes no arguments")
+		}
+		formatName, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		format, err := domain.ParseExportFormat(formatName)
+		if err != nil {
+			return err
+		}
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return err
+		}
+		query, err := storyQueryFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		result, err := svc.ExportStories(cmd.Context(), format, out, query)
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printExport(result) })
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [path]",
+	Short: "Import user stories from a CSV, JSON or plain text backlog",
+	Long:  "Import user stories from a CSV, JSON or newline-separated text file into the markdown file. Columns named id/uuid, description/story/title, category and status/state are recognised automatically; use --map to map other column names.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatName, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+		format, err := domain.ParseImportFormat(formatName, args[0])
+		if err != nil {
+			return err
+		}
+		mappingPairs, err := cmd.Flags().GetStringSlice("map")
+		if err != nil {
+			return err
+		}
+		mapping, err := domain.ParseImportMapping(mappingPairs)
+		if err != nil {
+			return err
+		}
+		duplicates, err := cmd.Flags().GetString("duplicates")
+		if err != nil {
+			return err
+		}
+		policy, err := application.ParseDuplicatePolicy(duplicates)
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		file := cmd.Flag("file").Value.String()
+		logf("Importing stories from %s into %s...\n", args[0], file)
+		result, err := svc.ImportStories(cmd.Context(), args[0], format, mapping, policy)
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printImport(result, file) })
+	},
+}
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Report groups of likely duplicate user stories and optionally merge them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("'dedupe' takes no arguments")
+		}
+		duplicates, err := duplicateCheckFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		merge, err := cmd.Flags().GetBool("merge")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		file := cmd.Flag("file").Value.String()
+		logf("Looking for duplicate stories in %s...\n", file)
+		result, err := svc.DedupeStories(cmd.Context(), duplicates, merge)
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printDedupe(result, merge, file) })
+	},
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit [uuid]",
+	Short: "Change the description, category, priority or estimate of a user story",
+	Long:  "Change the description, category, priority or estimate of a user story. The UUID may be shortened to any unique prefix.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var changes application.StoryChanges
+		if cmd.Flags().Changed("description") {
+			description, err := cmd.Flags().GetString("description")
+			if err != nil {
+				return err
+			}
+			changes.Description = &description
+		}
+		if cmd.Flags().Changed("category") {
+			category, err := cmd.Flags().GetString("category")
+			if err != nil {
+				return err
+			}
+			changes.Category = &category
+		}
+		if cmd.Flags().Changed("priority") {
+			name, err := cmd.Flags().GetString("priority")
+			if err != nil {
+				return err
+			}
+			priority, err := domain.ParsePriority(name)
+			if err != nil {
+				return err
+			}
+			changes.Priority = &priority
+		}
+		if cmd.Flags().Changed("estimate") {
+			value, err := cmd.Flags().GetString("estimate")
+			if err != nil {
+				return err
+			}
+			estimate, err := domain.ParseEstimate(value)
+			if err != nil {
+				return err
+			}
+			changes.Estimate = &estimate
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		edit, err := svc.EditUserStory(cmd.Context(), args[0], changes, yes)
+		if err != nil {
+			return err
+		}
+		return render(edit, func() {
+			if edit.Applied {
+				fmt.Println("Story updated.")
+			} else {
+				fmt.Println("Story not changed.")
+			}
+		})
+	},
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "remove [uuid]",
+	Short: "Remove a user story from the file",
+	Long:  "Remove a user story from the file. The UUID may be shortened to any unique prefix.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		removal, err := svc.RemoveUserStory(cmd.Context(), args[0], yes)
+		if err != nil {
+			return err
+		}
+		return render(removal, func() {
+			if removal.Removed {
+				fmt.Println("Story removed.")
+			} else {
+				fmt.Println("Story not removed.")
+			}
+		})
+	},
+}
+
+var criteriaCmd = &cobra.Command{
+	Use:   "criteria [uuid]",
+	Short: "Draft acceptance criteria for a user story with the LLM",
+	Long:  "Draft acceptance criteria for a user story with the LLM and add them to the story after confirmation. The UUID may be shortened to any unique prefix.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count, err := cmd.Flags().GetInt("num")
+		if err != nil {
+			return err
+		}
+		replace, err := cmd.Flags().GetBool("replace")
+		if err != nil {
+			return err
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		draft, err := svc.DraftAcceptanceCriteria(cmd.Context(), args[0], application.CriteriaOptions{Count: count, Replace: replace, SkipConfirm: yes})
+		if err != nil {
+			return err
+		}
+		return render(draft, func() { printCriteriaDraft(draft) })
+	},
+}
+
+var prioritizeCmd = &cobra.Command{
+	Use:   "prioritize",
+	Short: "Let the LLM propose a ranking of the open user stories",
+	Long:  "Let the LLM rank the stories that are not done or declined, with a rationale per story, and set their priorities after confirmation.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schemeName, err := cmd.Flags().GetString("scheme")
+		if err != nil {
+			return err
+		}
+		scheme, err := application.ParsePriorityScheme(schemeName)
+		if err != nil {
+			return err
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		result, err := svc.PrioritizeStories(cmd.Context(), application.PrioritizeOptions{Scheme: scheme, SkipConfirm: yes})
+		if err != nil {
+			return err
+		}
+		file := cmd.Flag("file").Value.String()
+		return render(result, func() { printPrioritization(result, file) })
+	},
+}
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Accept, decline, edit or recategorize the proposed user stories",
+	Long:  "Walk through the stories with the Proposed status, as added by 'add --propose' and 'generate', and accept, decline, edit, recategorize or skip each one. Who decided and when is recorded on the story.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reviewer, err := cmd.Flags().GetString("reviewer")
+		if err != nil {
+			return err
+		}
+		if reviewer == "" {
+			if reviewer, err = defaultReviewer(); err != nil {
+				return err
+			}
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		result, err := svc.ReviewProposedStories(cmd.Context(), application.ReviewOptions{Reviewer: reviewer})
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printReview(result) })
+	},
+}
+
+// defaultReviewer returns the reviewer from the config file, or else the login name.
+func defaultReviewer() (string, error) {
+	config, err := adapters.LoadConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+	if config.Reviewer != "" {
+		return config.Reviewer, nil
+	}
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username, nil
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name, nil
+	}
+	return "", fmt.Errorf("could not determine the reviewer, set --reviewer or 'reviewer' in the config file")
+}
+
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push the current markdown file as a project to the remote server",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		projectName, err := cmd.Flags().GetString("project-name")
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
 		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
 		file := cmd.Flag("file").Value.String()
-		fmt.Printf("Pushing project from %s...\n", file)
-		return svc.PushProject()
+		logf("Pushing project from %s...\n", file)
+		result, err := svc.PushProject(cmd.Context(), application.PushOptions{ProjectName: projectName, DryRun: dryRun})
+		if err != nil {
+			return err
+		}
+		return render(result, func() { printPush(result, file) })
+	},
+}
+
+// reportUsage prints what the LLM requests of command cost and appends it to the usage log.
+// It runs after failed commands too, since their requests were paid for all the same.
+// Replayed cassettes are not metered and offline runs are not logged.
+func reportUsage(command string) {
+	if meter == nil {
+		return
+	}
+	usage := meter.Usage()
+	if len(usage) == 0 {
+		return
+	}
+	prices := domain.DefaultPriceTable().Merge(meterConfig.Pricing)
+	records := domain.NewUsageRecords(usage, prices, command, meterProvider, time.Now())
+
+	fmt.Fprintln(os.Stderr, "\nLLM usage:")
+	var total float64
+	for _, record := range records {
+		line := fmt.Sprintf("  %s: %d requests, %d prompt + %d completion tokens", record.Model, record.Requests, record.PromptTokens, record.CompletionTokens)
+		if _, ok := prices.Cost(record.Model, 0, 0); ok {
+			line += fmt.Sprintf(", $%.4f", record.Cost)
+		} else {
+			line += ", no price configured"
+		}
+		fmt.Fprintln(os.Stderr, line)
+		total += record.Cost
+	}
+	fmt.Fprintf(os.Stderr, "  Total cost: $%.4f\n", total)
+
+	if !adapters.LogsUsage(meterProvider) {
+		return
+	}
+	logPath, err := usageLogPath(meterConfig)
+	if err == nil {
+		err = adapters.AppendUsageLog(logPath, records)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record usage: %v\n", err)
+	}
+}
+
+func usageLogPath(config domain.Config) (string, error) {
+	if config.UsageLog != "" {
+		return config.UsageLog, nil
+	}
+	path, err := adapters.DefaultUsageLogPath()
+	if err != nil {
+		return "", fmt.Errorf("could not determine usage log path: %w", err)
+	}
+	return path, nil
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report LLM token usage and cost by day and command",
+	// The report only reads the usage log, so it needs neither a story file nor an LLM.
+	PersistentPreRunE: setupWithoutService,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("'usage' takes no arguments")
+		}
+		days, err := cmd.Flags().GetInt("days")
+		if err != nil {
+			return err
+		}
+		config, err := adapters.LoadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		logPath, err := usageLogPath(config)
+		if err != nil {
+			return err
+		}
+		records, err := adapters.ReadUsageLog(logPath)
+		if err != nil {
+			return err
+		}
+
+		var since time.Time
+		if days > 0 {
+			year, month, day := time.Now().Date()
+			since = time.Date(year, month, day-days+1, 0, 0, 0, 0, time.Local)
+		}
+		rows := domain.SummarizeUsage(records, since)
+		return render(struct {
+			Usage []domain.UsageReportRow `json:"usage"`
+		}{rows}, func() { printUsageReport(rows) })
+	},
+}
+
+var categoryCmd = &cobra.Command{
+	Use:   "category",
+	Short: "List, rename and merge categories",
+}
+
+var categoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the categories with their number of stories",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("'category list' takes no arguments")
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		list, err := svc.ListCategories(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return render(list, func() { printCategoryList(list) })
+	},
+}
+
+var categoryRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Move all stories of a category to a new name",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		updateTaxonomy, err := cmd.Flags().GetBool("update-taxonomy")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		move, err := svc.RenameCategory(cmd.Context(), args[0], args[1], updateTaxonomy)
+		if err != nil {
+			return err
+		}
+		return render(move, func() { printCategoryMove(move) })
 	},
 }
 
+var categoryMergeCmd = &cobra.Command{
+	Use:   "merge <category>... --into <category>",
+	Short: "Move the stories of several categories into one",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		into, err := cmd.Flags().GetString("into")
+		if err != nil {
+			return err
+		}
+		if into == "" {
+			return fmt.Errorf("--into flag is required")
+		}
+		updateTaxonomy, err := cmd.Flags().GetBool("update-taxonomy")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		move, err := svc.MergeCategories(cmd.Context(), args, into, updateTaxonomy)
+		if err != nil {
+			return err
+		}
+		return render(move, func() { printCategoryMove(move) })
+	},
+}
+
+// loadPromptOverrides reads the prompts directory named by --prompts-dir or the config file.
+func loadPromptOverrides() (map[domain.PromptName]domain.PromptTemplate, error) {
+	dir := promptsDir
+	if dir == "" {
+		config, err := adapters.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		dir = config.PromptsDir
+	}
+	if dir == "" {
+		return nil, nil
+	}
+	return adapters.LoadPromptDir(dir)
+}
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List, show and export the prompts sent to the LLM",
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the prompts and where each one comes from",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("'prompts list' takes no arguments")
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		prompts, err := svc.EffectivePrompts()
+		if err != nil {
+			return err
+		}
+		for _, prompt := range prompts.Templates() {
+			fmt.Printf("%-20s  %s (%s)\n", prompt.Name, prompt.Name.Description(), prompt.Source)
+		}
+		return nil
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print the template of a prompt",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := domain.ParsePromptName(args[0])
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		prompts, err := svc.EffectivePrompts()
+		if err != nil {
+			return err
+		}
+		prompt, _ := prompts.Template(name)
+		fmt.Printf("# %s (%s)\n", prompt.Name, prompt.Source)
+		fmt.Println(strings.TrimRight(prompt.Text, "\n"))
+		return nil
+	},
+}
+
+var promptsExportCmd = &cobra.Command{
+	Use:   "export [dir]",
+	Short: "Write the prompts in effect to a directory for editing",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "prompts"
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		svc := cmd.Context().Value(svcKey).(*application.UserStoryService)
+		prompts, err := svc.EffectivePrompts()
+		if err != nil {
+			return err
+		}
+		written, err := adapters.WritePromptDir(dir, prompts.Templates(), force)
+		for _, path := range written {
+			fmt.Printf("Wrote %s\n", path)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Edit the files and pass --prompts-dir %s, or set prompts_dir in the config file, to use them.\n", dir)
+		return nil
+	},
+}
+
+// setupWithoutService replaces the root setup for commands that need neither a story file
+// nor an LLM: it checks the shared flags and applies --timeout but builds no service.
+func setupWithoutService(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(); err != nil {
+		return err
+	}
+	applyTimeout(cmd)
+	return nil
+}
+
+// applyTimeout bounds the context of cmd by --timeout, if it is set.
+func applyTimeout(cmd *cobra.Command) {
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+		cmd.SetContext(ctx)
+	}
+}
+
+// newRemoteService returns a service for the commands that talk to the remote server,
+// which never calls the LLM.
+func newRemoteService() *application.UserStoryService {
+	svc := application.NewUserStoryService(nil, "", adapters.NewLocalFileReader())
+	svc.SetInteraction(newTerminal())
+	return svc
+}
+
 var listRemoteCmd = &cobra.Command{
 	Use:   "listremote",
 	Short: "List all projects from the remote server",
+	// The remote commands need neither a story file nor an LLM.
+	PersistentPreRunE: setupWithoutService,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) != 0 {
 			return fmt.Errorf("'listremote' takes no arguments")
 		}
-		// We do not need the file flag or file context for this command
-		llmAPI := adapters.NewOpenAILLMService()
-		fileReader := adapters.NewLocalFileReader()
-		svc := application.NewUserStoryService(llmAPI, "", fileReader)
-		return svc.ListProjectsRemote()
+		projects, err := newRemoteService().ListProjectsRemote(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return render(struct {
+			Projects []domain.Project `json:"projects"`
+		}{projects}, func() { printRemoteProjects(projects) })
 	},
 }
 
 var getRemoteCmd = &cobra.Command{
-	Use:   "getremote",
-	Short: "Get a project by ID from the remote server and list its user stories",
+	Use:               "getremote",
+	Short:             "Get a project by ID from the remote server and list its user stories",
+	PersistentPreRunE: setupWithoutService,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := cmd.Flags().GetString("id")
 		if err != nil {
@@ -169,14 +944,117 @@ var getRemoteCmd = &cobra.Command{
 		if id == "" {
 			return fmt.Errorf("--id flag is required")
 		}
-		llmAPI := adapters.NewOpenAILLMService()
-		fileReader := adapters.NewLocalFileReader()
-		svc := application.NewUserStoryService(llmAPI, "", fileReader)
-		return svc.GetProjectRemote(id)
+		project, err := newRemoteService().GetProjectRemote(cmd.Context(), id)
+		if err != nil {
+			return err
+		}
+		return render(project, func() { printRemoteProject(project) })
 	},
 }
 
+// addStoryQueryFlags registers the filter and sort flags understood by storyQueryFromFlags.
+func addStoryQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("category", nil, "Only include stories in these categories (repeatable or comma-separated)")
+	cmd.Flags().StringSlice("status", nil, "Only include stories with these statuses; 'none' matches stories without a status")
+	cmd.Flags().String("text", "", "Only include stories whose description contains this text")
+	cmd.Flags().Bool("regex", false, "Treat --text as a regular expression")
+	cmd.Flags().StringSlice("id", nil, "Only include stories with these UUIDs or UUID prefixes")
+	cmd.Flags().String("sort", "", "Sort by file, category, status, description, id or priority")
+	cmd.Flags().Bool("desc", false, "Reverse the sort order")
+}
+
+func storyQueryFromFlags(cmd *cobra.Command) (application.StoryQuery, error) {
+	var query application.StoryQuery
+	var err error
+	if query.Categories, err = cmd.Flags().GetStringSlice("category"); err != nil {
+		return query, err
+	}
+	if query.Statuses, err = cmd.Flags().GetStringSlice("status"); err != nil {
+		return query, err
+	}
+	if query.Text, err = cmd.Flags().GetString("text"); err != nil {
+		return query, err
+	}
+	if query.TextIsRegex, err = cmd.Flags().GetBool("regex"); err != nil {
+		return query, err
+	}
+	if query.IDs, err = cmd.Flags().GetStringSlice("id"); err != nil {
+		return query, err
+	}
+	if query.Descending, err = cmd.Flags().GetBool("desc"); err != nil {
+		return query, err
+	}
+	sortBy, err := cmd.Flags().GetString("sort")
+	if err != nil {
+		return query, err
+	}
+	if query.SortBy, err = application.ParseSortField(sortBy); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// addDuplicateCheckFlags registers the flags understood by duplicateCheckFromFlags.
+func addDuplicateCheckFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("similarity", application.DefaultDuplicateThreshold, "Word similarity (0-1) from which stories count as duplicates")
+	cmd.Flags().Bool("semantic", false, "Also ask the LLM whether stories describe the same functionality")
+}
+
+func duplicateCheckFromFlags(cmd *cobra.Command) (application.DuplicateCheckOptions, error) {
+	threshold, err := cmd.Flags().GetFloat64("similarity")
+	if err != nil {
+		return application.DuplicateCheckOptions{}, err
+	}
+	if threshold <= 0 || threshold > 1 {
+		return application.DuplicateCheckOptions{}, fmt.Errorf("--similarity must be between 0 and 1")
+	}
+	semantic, err := cmd.Flags().GetBool("semantic")
+	if err != nil {
+		return application.DuplicateCheckOptions{}, err
+	}
+	return application.DuplicateCheckOptions{Threshold: threshold, Semantic: semantic}, nil
+}
+
 func init() {
+	addStoryQueryFlags(listCmd)
+	addStoryQueryFlags(exportCmd)
+	exportCmd.Flags().String("format", "json", "Export format: json, csv or yaml")
+	exportCmd.Flags().StringP("out", "o", "", "Path of the export file (default: stdout)")
+	importCmd.Flags().String("format", "", "Import format: csv, json or text (default: from the file extension)")
+	importCmd.Flags().StringSlice("map", nil, "Map a story field to a column, e.g. --map description=Summary (repeatable)")
+	importCmd.Flags().String("duplicates", "skip", "How to handle stories whose description already exists: skip or flag")
+	categorizeCmd.Flags().Int("concurrency", application.DefaultCategorizeConcurrency, "Number of LLM requests to run at the same time")
+	categorizeCmd.Flags().Int("batch-size", 0, "Categorize this many stories per LLM request (default: one request per story)")
 	generateCmd.Flags().IntP("num", "n", 1, "Number of user stories to generate")
+	addDuplicateCheckFlags(generateCmd)
+	generateCmd.Flags().BoolP("yes", "y", false, "Add the generated stories as Accepted instead of queueing them for review")
+	generateCmd.Flags().Bool("accept-all", false, "Same as --yes")
+	generateCmd.Flags().String("reviewer", "", "Name recorded as the decider with --yes (default: 'reviewer' from the config file or the login name)")
+	generateCmd.Flags().Bool("dry-run", false, "Print the stories that would be added without writing the file")
+	pushCmd.Flags().String("project-name", "", "Name of the project (default: project_name from the file metadata, or asked for)")
+	pushCmd.Flags().Bool("dry-run", false, "Print the project that would be pushed without sending it")
+	addDuplicateCheckFlags(addCmd)
+	addCmd.Flags().Bool("force", false, "Add the story without checking for duplicates")
+	addCmd.Flags().Bool("propose", false, "Add the story as Proposed, to be accepted or declined with review")
+	reviewCmd.Flags().String("reviewer", "", "Name recorded as the decider (default: 'reviewer' from the config file or the login name)")
+	addDuplicateCheckFlags(dedupeCmd)
+	dedupeCmd.Flags().Bool("merge", false, "Interactively choose which story of each group to keep and remove the others")
+	editCmd.Flags().String("description", "", "New description for the story")
+	editCmd.Flags().String("category", "", "New category for the story")
+	editCmd.Flags().String("priority", "", "New priority: Must, Should, Could, Won't or a number (empty to clear)")
+	editCmd.Flags().String("estimate", "", "New estimate in story points (empty to clear)")
+	editCmd.Flags().BoolP("yes", "y", false, "Apply the change without asking for confirmation")
+	removeCmd.Flags().BoolP("yes", "y", false, "Remove the story without asking for confirmation")
+	prioritizeCmd.Flags().String("scheme", string(application.PrioritySchemeMoSCoW), "Priorities to assign: moscow (Must, Should, Could, Won't) or numeric (the rank)")
+	prioritizeCmd.Flags().BoolP("yes", "y", false, "Apply the proposed priorities without asking for confirmation")
+	criteriaCmd.Flags().IntP("num", "n", 0, "Number of criteria to draft (default: let the LLM decide)")
+	criteriaCmd.Flags().Bool("replace", false, "Replace the story's existing criteria instead of adding to them")
+	criteriaCmd.Flags().BoolP("yes", "y", false, "Save the drafted criteria without asking for confirmation")
 	getRemoteCmd.Flags().String("id", "", "Project UUID to fetch from remote")
+	statusCmd.Flags().Bool("force", false, "Allow transitions that the workflow does not permit")
+	categoryRenameCmd.Flags().Bool("update-taxonomy", false, "Rename the category in the taxonomy of the front matter too")
+	categoryMergeCmd.Flags().String("into", "", "Category to move the stories into")
+	categoryMergeCmd.Flags().Bool("update-taxonomy", false, "Replace the merged categories in the taxonomy of the front matter too")
+	promptsExportCmd.Flags().Bool("force", false, "Replace prompt files that already exist")
+	usageCmd.Flags().Int("days", 30, "Report the last this many days, 0 for all recorded usage")
 }
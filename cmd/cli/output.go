@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/adapters"
+	"github.com/morgansundqvist/muserstory/internal/application"
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// outputFormat is set by --output.
+var outputFormat = outputText
+
+func checkOutputFormat() error {
+	switch outputFormat {
+	case outputText, outputJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown output format '%s', expected text or json", outputFormat)
+}
+
+// logOut is where progress and other human messages go: stdout, or stderr when stdout is
+// reserved for JSON.
+func logOut() io.Writer {
+	if outputFormat == outputJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// newTerminal is the interaction the service prompts through: questions on the terminal,
+// or none at all with --non-interactive, which makes commands fail instead of waiting.
+func newTerminal() ports.Interaction {
+	if nonInteractive {
+		return adapters.NewConsoleInteraction(nil, logOut())
+	}
+	return adapters.NewConsoleInteraction(os.Stdin, logOut())
+}
+
+func logf(format string, args ...interface{}) {
+	fmt.Fprintf(logOut(), format, args...)
+}
+
+// render writes result to stdout as JSON with --output json, and otherwise prints it as
+// text with human.
+func render(result interface{}, human func()) error {
+	if outputFormat != outputJSON {
+		human()
+		return nil
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// reportError prints the error a command failed with, as a JSON object on stdout with
+// --output json so scripts read results and errors from the same stream.
+func reportError(err error) {
+	if outputFormat == outputJSON {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+}
+
+// printStoryList prints the stories as a tree of categories with the number of stories per
+// node, unless the query sorts by another field, in which case a flat list is printed.
+func printStoryList(list *application.StoryList, query application.StoryQuery) {
+	if list.Total == 0 {
+		if list.Summary == "" {
+			fmt.Println("No user stories found in the file.")
+		} else {
+			fmt.Println("No user stories found in the file (summary is present).")
+		}
+		return
+	}
+	if len(list.Stories) == 0 {
+		fmt.Println("No user stories match the given filters.")
+		return
+	}
+	if !query.IsEmpty() {
+		fmt.Printf("Showing %d of %d stories.\n", len(list.Stories), list.Total)
+	}
+
+	fmt.Println("User Stories:")
+	if query.SortBy != "" && query.SortBy != application.SortByFile && query.SortBy != application.SortByCategory {
+		for _, story := range list.Stories {
+			printStoryLine("", story, true)
+		}
+		return
+	}
+
+	tree := domain.BuildCategoryTree(list.Stories)
+	tree.SortChildren(query.Descending)
+	for i, node := range tree.Children {
+		printCategoryNode(node, 0)
+		if i < len(tree.Children)-1 {
+			fmt.Println()
+		}
+	}
+}
+
+// printCategoryNode prints a category with its story count, which includes the nested
+// categories, followed by its stories and the nested categories indented below it.
+func printCategoryNode(node *domain.CategoryNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Printf("%s%s (%d)\n", indent, node.Name, node.Count())
+	for _, story := range node.Stories {
+		printStoryLine(indent+"  ", story, false)
+	}
+	for _, child := range node.Children {
+		printCategoryNode(child, depth+1)
+	}
+}
+
+func printStoryLine(indent string, story domain.UserStory, withCategory bool) {
+	var line strings.Builder
+	line.WriteString(indent + "- " + story.Description)
+	if withCategory {
+		line.WriteString(fmt.Sprintf(" [Category: %s]", story.Category))
+	}
+	if story.Status != "" {
+		line.WriteString(fmt.Sprintf(" [Status: %s]", story.Status))
+	}
+	if story.Priority != "" {
+		line.WriteString(fmt.Sprintf(" [Priority: %s]", story.Priority))
+	}
+	if story.Estimate > 0 {
+		line.WriteString(fmt.Sprintf(" [Estimate: %s]", domain.FormatEstimate(story.Estimate)))
+	}
+	line.WriteString(fmt.Sprintf(" [UUID: %s]", story.ID))
+	fmt.Println(line.String())
+}
+
+func printAddedStory(result *application.AddedStory) {
+	switch {
+	case !result.Added:
+		fmt.Println("Story not added.")
+	case result.Story.Status == domain.StatusProposed:
+		fmt.Printf("User story proposed: \"%s\" [Category: %s]. Run review to accept or decline it.\n", result.Story.Description, result.Story.Category)
+	default:
+		fmt.Printf("User story added: \"%s\" [Category: %s]\n", result.Story.Description, result.Story.Category)
+	}
+}
+
+func printCategorization(result *application.CategorizationResult) {
+	if len(result.Stories) == 0 {
+		fmt.Println("No stories to categorize.")
+		return
+	}
+	fmt.Println("Current stories and their categories:")
+	for _, story := range result.Stories {
+		fmt.Printf("  - \"%s\" [Category: %s]\n", story.Description, story.Category)
+	}
+	fmt.Println("Categorization process complete.")
+}
+
+func printGeneratedStories(result *application.GeneratedStories, file string) {
+	count := len(result.Stories)
+	switch {
+	case count == 0:
+		return
+	case result.DryRun:
+		fmt.Printf("Dry run: %d new user stories would be added to %s. Nothing was written.\n", count, file)
+	case result.Stories[0].Status == domain.StatusAccepted:
+		fmt.Printf("%d new user stories have been generated, categorized, and accepted by %s in %s.\n", count, result.Stories[0].DecidedBy, file)
+	default:
+		fmt.Printf("%d new user stories have been generated, categorized, and proposed in %s. Run review to accept or decline them.\n", count, file)
+	}
+}
+
+func printSummary(summary *application.Summary) {
+	if summary.Text == "" {
+		return
+	}
+	fmt.Println("# Summary")
+	fmt.Println(summary.Text)
+	fmt.Println("\nFile has been updated with the new summary and existing stories.")
+}
+
+func printRemoteProjects(projects []domain.Project) {
+	if len(projects) == 0 {
+		fmt.Println("No remote projects found.")
+		return
+	}
+	fmt.Println("Remote Projects:")
+	for _, project := range projects {
+		fmt.Printf("- %s (UUID: %s)\n", project.Name, project.ID)
+	}
+}
+
+func printRemoteProject(project *domain.Project) {
+	fmt.Printf("Project: %s (UUID: %s)\n", project.Name, project.ID)
+	if len(project.UserStories) == 0 {
+		fmt.Println("No user stories found for this project.")
+		return
+	}
+	fmt.Println("User Stories:")
+	for _, story := range project.UserStories {
+		if story.Status != "" {
+			fmt.Printf("- %s [Category: %s] [Status: %s]\n", story.Description, story.Category, story.Status)
+			continue
+		}
+		fmt.Printf("- %s [Category: %s]\n", story.Description, story.Category)
+	}
+}
+
+func printStatusChange(change *application.StatusChange) {
+	if change.Previous == "" {
+		fmt.Printf("Status set to '%s' for \"%s\"\n", change.Story.Status, change.Story.Description)
+		return
+	}
+	fmt.Printf("Status changed from '%s' to '%s' for \"%s\"\n", change.Previous, change.Story.Status, change.Story.Description)
+}
+
+// printExport prints the export itself when it was not written to a file.
+func printExport(result *application.ExportResult) {
+	if result.Path == "" {
+		fmt.Print(result.Content)
+		return
+	}
+	fmt.Printf("Exported %d stories to %s as %s.\n", result.Stories, result.Path, result.Format)
+}
+
+func printImport(result *application.ImportResult, file string) {
+	fmt.Printf("Imported %d of %d stories into %s.\n", result.Imported, result.Total, file)
+	if len(result.Skipped) > 0 {
+		fmt.Printf("Skipped %d entries:\n", len(result.Skipped))
+		for _, reason := range result.Skipped {
+			fmt.Printf("  - %s\n", reason)
+		}
+	}
+	if len(result.Flagged) > 0 {
+		fmt.Printf("Imported %d possible duplicates, please review:\n", len(result.Flagged))
+		for _, description := range result.Flagged {
+			fmt.Printf("  - \"%s\"\n", description)
+		}
+	}
+}
+
+// printDedupe lists the groups of likely duplicates, or with merge, which the groups were
+// already shown while picking, how many stories were removed.
+func printDedupe(result *application.DedupeResult, merge bool, file string) {
+	if len(result.Clusters) == 0 {
+		fmt.Println("No likely duplicates found.")
+		return
+	}
+	if merge {
+		if result.Removed > 0 {
+			fmt.Printf("Removed %d duplicate stories from %s.\n", result.Removed, file)
+		}
+		return
+	}
+	fmt.Printf("Found %d groups of likely duplicates.\n", len(result.Clusters))
+	for i, cluster := range result.Clusters {
+		fmt.Printf("\nGroup %d/%d:\n", i+1, len(result.Clusters))
+		for j, story := range cluster.Stories {
+			fmt.Printf("  %d. %s [Category: %s] [UUID: %s]\n", j+1, story.Description, story.Category, story.ID)
+		}
+	}
+}
+
+func printCriteriaDraft(draft *application.CriteriaDraft) {
+	switch {
+	case len(draft.Criteria) == 0:
+		fmt.Println("LLM did not draft any new acceptance criteria.")
+	case !draft.Saved:
+		fmt.Println("Acceptance criteria not saved.")
+	default:
+		fmt.Printf("%d acceptance criteria saved to story %s.\n", len(draft.Criteria), draft.Story.ID)
+	}
+}
+
+func printPrioritization(result *application.PrioritizationResult, file string) {
+	switch {
+	case result.Open == 0:
+		fmt.Println("No open user stories to prioritize.")
+	case len(result.Proposals) == 0:
+		fmt.Println("LLM did not rank any of the stories.")
+	case !result.Applied:
+		fmt.Println("Priorities not changed.")
+	default:
+		fmt.Printf("Priorities of %d stories have been updated in %s.\n", len(result.Proposals), file)
+	}
+}
+
+func printReview(result *application.ReviewResult) {
+	if result.Accepted+result.Declined+result.Remaining == 0 {
+		fmt.Println("No proposed user stories to review.")
+		return
+	}
+	fmt.Printf("\nReview finished: %d accepted, %d declined, %d still proposed.\n", result.Accepted, result.Declined, result.Remaining)
+}
+
+func printCategoryList(list *application.CategoryList) {
+	if len(list.Categories) == 0 {
+		fmt.Println("No categories found.")
+		return
+	}
+	for _, count := range list.Categories {
+		line := fmt.Sprintf("%-30s %d", count.Name, count.Stories)
+		if list.HasTaxonomy && !count.Declared {
+			line += " (not in taxonomy)"
+		}
+		fmt.Println(line)
+	}
+}
+
+func printCategoryMove(move *application.CategoryMove) {
+	fmt.Printf("Moved %d stories into category '%s'.\n", move.Moved, move.Into)
+	if move.TaxonomyUpdated {
+		fmt.Println("Taxonomy updated.")
+	} else if !move.Declared {
+		fmt.Printf("Note: category '%s' is not in the taxonomy, use --update-taxonomy to change the taxonomy as well.\n", move.Into)
+	}
+}
+
+func printPush(result *application.PushResult, file string) {
+	switch {
+	case result.DryRun && result.New:
+		fmt.Printf("Dry run: would push new project \"%s\" with %d stories to %s. Nothing was sent or written.\n", result.ProjectName, result.Stories, result.URL)
+	case result.DryRun:
+		fmt.Printf("Dry run: would push project \"%s\" (UUID: %s) with %d stories to %s. Nothing was sent or written.\n", result.ProjectName, result.ProjectID, result.Stories, result.URL)
+	default:
+		fmt.Printf("Project pushed and metadata updated in %s\n", file)
+	}
+}
+
+func printUsageReport(rows []domain.UsageReportRow) {
+	if len(rows) == 0 {
+		fmt.Println("No LLM usage recorded.")
+		return
+	}
+	fmt.Printf("%-10s  %-20s  %8s  %12s  %12s  %10s\n", "Day", "Command", "Requests", "Prompt", "Completion", "Cost")
+	var total domain.UsageReportRow
+	for _, row := range rows {
+		fmt.Printf("%-10s  %-20s  %8d  %12d  %12d  %10s\n", row.Day, row.Command, row.Requests, row.PromptTokens, row.CompletionTokens, fmt.Sprintf("$%.4f", row.Cost))
+		total.Requests += row.Requests
+		total.PromptTokens += row.PromptTokens
+		total.CompletionTokens += row.CompletionTokens
+		total.Cost += row.Cost
+	}
+	fmt.Printf("%-10s  %-20s  %8d  %12d  %12d  %10s\n", "Total", "", total.Requests, total.PromptTokens, total.CompletionTokens, fmt.Sprintf("$%.4f", total.Cost))
+}
@@ -39,6 +39,7 @@ func main() {
 	api.Post("/projects", projectHandler.CreateProject)
 	api.Get("/projects", projectHandler.GetProjects)
 	api.Get("/projects/:id", projectHandler.GetProjectByID)
+	api.Get("/projects/:id/stories", projectHandler.GetProjectStories)
 
 	log.Printf("Starting server on http://localhost:%s\n", port)
 	if err := app.Listen(":" + port); err != nil {
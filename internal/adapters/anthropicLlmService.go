@@ -0,0 +1,211 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+	anthropicMaxTokens      = 4096
+)
+
+var anthropicDefaultModels = map[domain.ModelType]string{
+	domain.ModelTypeSimple:            "claude-3-5-haiku-latest",
+	domain.ModelTypeAdvanced:          "claude-sonnet-4-0",
+	domain.ModelTypeReasoningSimple:   "claude-sonnet-4-0",
+	domain.ModelTypeReasoningAdvanced: "claude-opus-4-0",
+}
+
+// AnthropicLLMService talks to the Anthropic Messages API. Structured output for
+// AskAdvanced is obtained by forcing a tool call whose input schema is the requested schema.
+type AnthropicLLMService struct {
+	apiKey     string
+	baseURL    string
+	config     domain.LLMProviderConfig
+	httpClient *http.Client
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Model   string                  `json:"model"`
+	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
+}
+
+func (r *anthropicResponse) usage() domain.LLMUsage {
+	return domain.LLMUsage{Model: r.Model, PromptTokens: r.Usage.InputTokens, CompletionTokens: r.Usage.OutputTokens}
+}
+
+// NewAnthropicLLMService creates a service for the Anthropic API. The key defaults to ANTHROPIC_API_KEY.
+func NewAnthropicLLMService(cfg domain.LLMProviderConfig) *AnthropicLLMService {
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	return &AnthropicLLMService{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		config:     cfg,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (s *AnthropicLLMService) modelSettings(modelType domain.ModelType) domain.ModelSettings {
+	defaultModel, ok := anthropicDefaultModels[modelType]
+	if !ok {
+		defaultModel = anthropicDefaultModels[domain.ModelTypeAdvanced]
+	}
+	settings := s.config.ModelSettingsFor(modelType, defaultModel)
+	if settings.MaxTokens == 0 {
+		settings.MaxTokens = anthropicMaxTokens
+	}
+	return settings
+}
+
+func (s *AnthropicLLMService) ModelName(modelType domain.ModelType) string {
+	return s.modelSettings(modelType).Model
+}
+
+func (s *AnthropicLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	settings := s.modelSettings(input.ModelType)
+	response, err := s.send(ctx, settings, anthropicRequest{
+		Model:       settings.Model,
+		MaxTokens:   settings.MaxTokens,
+		Temperature: settings.Temperature,
+		System:      input.SystemMessage,
+		Messages:    []anthropicMessage{{Role: "user", Content: input.UserMessage}},
+	})
+	if err != nil {
+		return domain.LLMResponse{}, err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return domain.LLMResponse{}, &domain.LLMError{Kind: domain.ErrLLMEmptyResponse}
+	}
+	return domain.LLMResponse{Content: text.String(), Usage: response.usage()}, nil
+}
+
+func (s *AnthropicLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	settings := s.modelSettings(input.ModelType)
+	response, err := s.send(ctx, settings, anthropicRequest{
+		Model:       settings.Model,
+		MaxTokens:   settings.MaxTokens,
+		Temperature: settings.Temperature,
+		System:      input.SystemMessage,
+		Messages:    []anthropicMessage{{Role: "user", Content: input.UserMessage}},
+		Tools: []anthropicTool{{
+			Name:        input.SchemaName,
+			Description: input.SchemaDescription,
+			InputSchema: input.Schema,
+		}},
+		ToolChoice: &anthropicToolChoice{Type: "tool", Name: input.SchemaName},
+	})
+	if err != nil {
+		return domain.LLMResponse{}, err
+	}
+
+	for _, block := range response.Content {
+		if block.Type == "tool_use" && block.Name == input.SchemaName {
+			return domain.LLMResponse{Content: string(block.Input), Usage: response.usage()}, nil
+		}
+	}
+	return domain.LLMResponse{}, &domain.LLMError{
+		Kind: domain.ErrLLMEmptyResponse,
+		Err:  fmt.Errorf("anthropic response did not contain a '%s' tool call", input.SchemaName),
+	}
+}
+
+func (s *AnthropicLLMService) send(ctx context.Context, settings domain.ModelSettings, request anthropicRequest) (*anthropicResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal anthropic request: %w", err)
+	}
+
+	requestCtx, cancel := requestContext(ctx, settings)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(requestCtx, "POST", s.baseURL+"/v1/messages", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", s.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, newTransportError(ctx, fmt.Errorf("failed to get anthropic message: %w", err))
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newHTTPError(resp.StatusCode, resp.Header, fmt.Errorf("anthropic request failed, status: %s: %s", resp.Status, strings.TrimSpace(string(responseBody))))
+	}
+
+	var response anthropicResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode anthropic response: %w", err)
+	}
+	return &response, nil
+}
@@ -0,0 +1,199 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+const (
+	DefaultCacheTTL        = 7 * 24 * time.Hour
+	DefaultCacheMaxEntries = 5000
+	// cacheSweepInterval is the number of writes after which expired entries are removed
+	// even though the cache is not full.
+	cacheSweepInterval = 500
+)
+
+type cacheEntry struct {
+	CreatedAt time.Time `json:"created_at"`
+	Response  string    `json:"response"`
+}
+
+// CachingLLMService keeps responses of the wrapped service on disk, one file per request
+// keyed by domain.CacheKey. Entries expire after the TTL and the oldest entries are
+// evicted once there are more than the maximum. The cache is best effort: unreadable or
+// unwritable entries are treated as misses.
+type CachingLLMService struct {
+	inner      ports.LLMService
+	dir        string
+	provider   string
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+	mu         sync.Mutex
+	// entries is the number of entries in dir, counted on the first write; -1 until then.
+	entries int
+	// writes counts the writes since the last sweep of the directory.
+	writes int
+}
+
+// NewCachingLLMService wraps inner, the service of provider, with a cache stored in dir.
+// Zero ttl and maxEntries use DefaultCacheTTL and DefaultCacheMaxEntries.
+func NewCachingLLMService(inner ports.LLMService, dir string, provider string, ttl time.Duration, maxEntries int) *CachingLLMService {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	return &CachingLLMService{inner: inner, dir: dir, provider: provider, ttl: ttl, maxEntries: maxEntries, now: time.Now, entries: -1}
+}
+
+// DefaultCacheDir returns <user cache dir>/muserstory/llm.
+func DefaultCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "muserstory", "llm"), nil
+}
+
+func (s *CachingLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	key := s.key(input.ModelType, domain.PromptHash(input.ModelType, input.SystemMessage, input.UserMessage, ""), nil)
+	return s.cached(key, func() (domain.LLMResponse, error) {
+		return s.inner.AskSimple(ctx, input)
+	})
+}
+
+func (s *CachingLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	key := s.key(input.ModelType, domain.PromptHash(input.ModelType, input.SystemMessage, input.UserMessage, input.SchemaName), input.Schema)
+	return s.cached(key, func() (domain.LLMResponse, error) {
+		return s.inner.AskAdvanced(ctx, input)
+	})
+}
+
+func (s *CachingLLMService) key(modelType domain.ModelType, promptHash string, schema interface{}) string {
+	return domain.CacheKey(s.provider, modelNameOf(s.inner, modelType), promptHash, schema)
+}
+
+// cached answers hits with an empty usage, since they cost nothing.
+func (s *CachingLLMService) cached(key string, ask func() (domain.LLMResponse, error)) (domain.LLMResponse, error) {
+	if content, ok := s.load(key); ok {
+		return domain.LLMResponse{Content: content}, nil
+	}
+	response, err := ask()
+	if err != nil {
+		return domain.LLMResponse{}, err
+	}
+	s.store(key, response.Content)
+	return response, nil
+}
+
+func (s *CachingLLMService) entryPath(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+func (s *CachingLLMService) load(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.entryPath(key))
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return "", false
+	}
+	if s.now().Sub(entry.CreatedAt) > s.ttl {
+		if os.Remove(s.entryPath(key)) == nil && s.entries > 0 {
+			s.entries--
+		}
+		return "", false
+	}
+	return entry.Response, true
+}
+
+func (s *CachingLLMService) store(key string, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(cacheEntry{CreatedAt: s.now(), Response: response})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return
+	}
+	if s.entries < 0 {
+		s.evict()
+	}
+	_, statErr := os.Stat(s.entryPath(key))
+	tmpPath := s.entryPath(key) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmpPath, s.entryPath(key)); err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+	if statErr != nil {
+		s.entries++
+	}
+	// Scanning the directory is linear in its size, so it only happens when the cache is
+	// over its limit or every cacheSweepInterval writes.
+	s.writes++
+	if s.entries > s.maxEntries || s.writes >= cacheSweepInterval {
+		s.evict()
+	}
+}
+
+// evict removes expired entries and then the oldest ones until at most maxEntries remain,
+// and recounts the entries.
+func (s *CachingLLMService) evict() {
+	s.writes = 0
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	type cachedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []cachedFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(s.dir, dirEntry.Name())
+		if s.now().Sub(info.ModTime()) > s.ttl {
+			os.Remove(path)
+			continue
+		}
+		files = append(files, cachedFile{path: path, modTime: info.ModTime()})
+	}
+	s.entries = len(files)
+	if len(files) <= s.maxEntries {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files[:len(files)-s.maxEntries] {
+		if os.Remove(file.path) == nil {
+			s.entries--
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+type countingLLMService struct {
+	calls int
+	model string
+}
+
+func (c *countingLLMService) ModelName(modelType domain.ModelType) string {
+	return c.model
+}
+
+func (c *countingLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	c.calls++
+	return domain.LLMResponse{Content: "answer to " + input.UserMessage, Usage: domain.LLMUsage{Model: "counting", PromptTokens: 10}}, nil
+}
+
+func (c *countingLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	c.calls++
+	return domain.LLMResponse{Content: `{"schema":"` + input.SchemaName + `"}`, Usage: domain.LLMUsage{Model: "counting", PromptTokens: 10}}, nil
+}
+
+func TestCachingLLMService(t *testing.T) {
+	ctx := t.Context()
+	inner := &countingLLMService{}
+	dir := t.TempDir()
+	cache := NewCachingLLMService(inner, dir, "counting", time.Hour, 0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	input := domain.LLMSimpleInput{SystemMessage: "Categorize.", UserMessage: "story", ModelType: domain.ModelTypeSimple}
+	for i := 0; i < 2; i++ {
+		got, err := cache.AskSimple(ctx, input)
+		if err != nil || got.Content != "answer to story" {
+			t.Fatalf("AskSimple() = %q, %v", got.Content, err)
+		}
+		if i == 1 && got.Usage.PromptTokens != 0 {
+			t.Errorf("cache hit reported usage %+v", got.Usage)
+		}
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner called %d times, want 1", inner.calls)
+	}
+
+	// A different schema name is a different request.
+	advanced := domain.LLMAdvancedInput{SystemMessage: "Categorize.", UserMessage: "story", ModelType: domain.ModelTypeSimple, SchemaName: "A"}
+	cache.AskAdvanced(ctx, advanced)
+	advanced.SchemaName = "B"
+	cache.AskAdvanced(ctx, advanced)
+	if inner.calls != 3 {
+		t.Errorf("inner called %d times, want 3", inner.calls)
+	}
+
+	// So is a schema that differs only in its body, such as another taxonomy enum.
+	advanced.Schema = map[string]interface{}{"enum": []string{"Auth", "Billing"}}
+	cache.AskAdvanced(ctx, advanced)
+	cache.AskAdvanced(ctx, advanced)
+	advanced.Schema = map[string]interface{}{"enum": []string{"Auth"}}
+	cache.AskAdvanced(ctx, advanced)
+	if inner.calls != 5 {
+		t.Errorf("inner called %d times, want 5", inner.calls)
+	}
+
+	// A fresh service on the same directory reuses the stored responses.
+	reopened := NewCachingLLMService(inner, dir, "counting", time.Hour, 0)
+	reopened.AskSimple(ctx, input)
+	if inner.calls != 5 {
+		t.Errorf("reopened cache missed, inner called %d times", inner.calls)
+	}
+
+	// Another model does not reuse the answers of the previous one.
+	inner.model = "bigger"
+	cache.AskSimple(ctx, input)
+	if inner.calls != 6 {
+		t.Errorf("answer of another model was used, inner called %d times", inner.calls)
+	}
+
+	now = now.Add(2 * time.Hour)
+	cache.AskSimple(ctx, input)
+	if inner.calls != 7 {
+		t.Errorf("expired entry was used, inner called %d times", inner.calls)
+	}
+}
+
+func TestCachingLLMServiceEviction(t *testing.T) {
+	ctx := t.Context()
+	inner := &countingLLMService{}
+	dir := t.TempDir()
+	cache := NewCachingLLMService(inner, dir, "counting", time.Hour, 2)
+
+	for i, message := range []string{"first", "second", "third"} {
+		cache.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: message, ModelType: domain.ModelTypeSimple})
+		// Give the entries distinct modification times so the oldest is evicted.
+		path := cache.entryPath(cache.key(domain.ModelTypeSimple, domain.PromptHash(domain.ModelTypeSimple, "", message, ""), nil))
+		modTime := time.Now().Add(time.Duration(i-3) * time.Minute)
+		os.Chtimes(path, modTime, modTime)
+	}
+	cache.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: "fourth", ModelType: domain.ModelTypeSimple})
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 || cache.entries != 2 {
+		t.Fatalf("got %d cache entries, counted %d, want 2", len(entries), cache.entries)
+	}
+	calls := inner.calls
+	cache.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: "third", ModelType: domain.ModelTypeSimple})
+	if inner.calls != calls {
+		t.Error("newest entry was evicted")
+	}
+}
+
+func TestCachingLLMServiceCountsExistingEntries(t *testing.T) {
+	ctx := t.Context()
+	inner := &countingLLMService{}
+	dir := t.TempDir()
+	first := NewCachingLLMService(inner, dir, "counting", time.Hour, 2)
+	first.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: "first", ModelType: domain.ModelTypeSimple})
+	first.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: "second", ModelType: domain.ModelTypeSimple})
+
+	// A new service counts the entries already on disk before it adds to them.
+	second := NewCachingLLMService(inner, dir, "counting", time.Hour, 2)
+	second.AskSimple(ctx, domain.LLMSimpleInput{UserMessage: "third", ModelType: domain.ModelTypeSimple})
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("got %d cache entries, want 2", len(entries))
+	}
+}
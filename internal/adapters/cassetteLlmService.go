@@ -0,0 +1,167 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+type CassetteMode string
+
+const (
+	// CassetteRecord forwards requests to the wrapped service and stores every answer.
+	CassetteRecord CassetteMode = "record"
+	// CassetteReplay answers from the cassette only and never touches the network.
+	CassetteReplay CassetteMode = "replay"
+)
+
+func ParseCassetteMode(name string) (CassetteMode, error) {
+	switch mode := CassetteMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case CassetteRecord, CassetteReplay:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown cassette mode '%s', expected record or replay", name)
+}
+
+// CassetteInteraction is one recorded request and the response it got.
+type CassetteInteraction struct {
+	ModelType     domain.ModelType `json:"model_type"`
+	SystemMessage string           `json:"system_message"`
+	UserMessage   string           `json:"user_message"`
+	SchemaName    string           `json:"schema_name,omitempty"`
+	Response      string           `json:"response"`
+}
+
+type cassetteFile struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteLLMService records LLM requests and responses to a cassette file, or replays
+// them from it. Replayed requests must match a recorded one exactly; identical requests
+// are answered in recording order, and each recorded answer is used only once.
+type CassetteLLMService struct {
+	inner        ports.LLMService
+	path         string
+	mode         CassetteMode
+	interactions []CassetteInteraction
+	used         []bool
+	mu           sync.Mutex
+}
+
+// NewRecordingLLMService wraps inner and writes every interaction to path, replacing
+// any cassette already there. The file is saved after each request so interrupted
+// runs keep what was recorded.
+func NewRecordingLLMService(inner ports.LLMService, path string) *CassetteLLMService {
+	return &CassetteLLMService{inner: inner, path: path, mode: CassetteRecord}
+}
+
+// NewReplayingLLMService loads the cassette at path and answers requests from it.
+func NewReplayingLLMService(path string) (*CassetteLLMService, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette %s: %w", path, err)
+	}
+	var cassette cassetteFile
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+	}
+	return &CassetteLLMService{
+		path:         path,
+		mode:         CassetteReplay,
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+func (s *CassetteLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	request := CassetteInteraction{
+		ModelType:     input.ModelType,
+		SystemMessage: input.SystemMessage,
+		UserMessage:   input.UserMessage,
+	}
+	return s.handle(request, func() (domain.LLMResponse, error) {
+		return s.inner.AskSimple(ctx, input)
+	})
+}
+
+func (s *CassetteLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	request := CassetteInteraction{
+		ModelType:     input.ModelType,
+		SystemMessage: input.SystemMessage,
+		UserMessage:   input.UserMessage,
+		SchemaName:    input.SchemaName,
+	}
+	return s.handle(request, func() (domain.LLMResponse, error) {
+		return s.inner.AskAdvanced(ctx, input)
+	})
+}
+
+func (s *CassetteLLMService) handle(request CassetteInteraction, ask func() (domain.LLMResponse, error)) (domain.LLMResponse, error) {
+	if s.mode == CassetteReplay {
+		return s.replay(request)
+	}
+
+	response, err := ask()
+	if err != nil {
+		return domain.LLMResponse{}, err
+	}
+	request.Response = response.Content
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interactions = append(s.interactions, request)
+	if err := s.save(); err != nil {
+		return domain.LLMResponse{}, err
+	}
+	return response, nil
+}
+
+// replay answers with an empty usage, since replayed requests never reach a provider.
+func (s *CassetteLLMService) replay(request CassetteInteraction) (domain.LLMResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recordedBefore := false
+	for i, recorded := range s.interactions {
+		if recorded.ModelType != request.ModelType || recorded.SystemMessage != request.SystemMessage ||
+			recorded.UserMessage != request.UserMessage || recorded.SchemaName != request.SchemaName {
+			continue
+		}
+		if !s.used[i] {
+			s.used[i] = true
+			return domain.LLMResponse{Content: recorded.Response}, nil
+		}
+		recordedBefore = true
+	}
+	hash := domain.PromptHash(request.ModelType, request.SystemMessage, request.UserMessage, request.SchemaName)
+	if recordedBefore {
+		return domain.LLMResponse{}, fmt.Errorf("cassette %s has no recorded responses left for request %s, it was sent more often than recorded", s.path, hash)
+	}
+	return domain.LLMResponse{}, fmt.Errorf("cassette %s has no recorded response for request %s: %s request (schema %q) with user message %q",
+		s.path, hash, request.ModelType, request.SchemaName, truncate(request.UserMessage, 80))
+}
+
+func (s *CassetteLLMService) save() error {
+	data, err := json.MarshalIndent(cassetteFile{Interactions: s.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing cassette %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "..."
+}
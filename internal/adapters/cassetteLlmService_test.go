@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	offline, _ := NewOfflineLLMService(domain.LLMProviderConfig{})
+	offline.AddFixture(domain.PromptHash(domain.ModelTypeSimple, "Summarize.", "Stories", ""), "First summary")
+
+	simple := domain.LLMSimpleInput{SystemMessage: "Summarize.", UserMessage: "Stories", ModelType: domain.ModelTypeSimple}
+	advanced := domain.LLMAdvancedInput{
+		SystemMessage: "Generate categories.",
+		UserMessage:   "Stories",
+		ModelType:     domain.ModelTypeSimple,
+		SchemaName:    "GeneratePossibleCategories",
+	}
+
+	recorder := NewRecordingLLMService(offline, path)
+	if _, err := recorder.AskSimple(ctx, simple); err != nil {
+		t.Fatalf("AskSimple() error = %v", err)
+	}
+	offline.AddFixture(domain.PromptHash(domain.ModelTypeSimple, "Summarize.", "Stories", ""), "Second summary")
+	if _, err := recorder.AskSimple(ctx, simple); err != nil {
+		t.Fatalf("AskSimple() error = %v", err)
+	}
+	recordedCategories, err := recorder.AskAdvanced(ctx, advanced)
+	if err != nil {
+		t.Fatalf("AskAdvanced() error = %v", err)
+	}
+
+	player, err := NewReplayingLLMService(path)
+	if err != nil {
+		t.Fatalf("NewReplayingLLMService() error = %v", err)
+	}
+	for _, want := range []string{"First summary", "Second summary"} {
+		if got, err := player.AskSimple(ctx, simple); err != nil || got.Content != want {
+			t.Errorf("AskSimple() = %q, %v, want %q", got.Content, err, want)
+		}
+	}
+	hash := domain.PromptHash(domain.ModelTypeSimple, "Summarize.", "Stories", "")
+	if _, err := player.AskSimple(ctx, simple); err == nil || !strings.Contains(err.Error(), hash) {
+		t.Errorf("AskSimple() after the recorded answers ran out error = %v, want one naming %s", err, hash)
+	}
+	if got, err := player.AskAdvanced(ctx, advanced); err != nil || got.Content != recordedCategories.Content {
+		t.Errorf("AskAdvanced() = %q, %v, want %q", got.Content, err, recordedCategories.Content)
+	}
+
+	advanced.SchemaName = "Other"
+	if _, err := player.AskAdvanced(ctx, advanced); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("AskAdvanced() with an unrecorded schema error = %v", err)
+	}
+	simple.UserMessage = "Changed stories"
+	if _, err := player.AskSimple(ctx, simple); err == nil {
+		t.Error("expected an error for an unrecorded request")
+	}
+}
+
+func TestCassetteReplayMissingFile(t *testing.T) {
+	if _, err := NewReplayingLLMService(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing cassette")
+	}
+}
@@ -0,0 +1,53 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+const projectConfigFile = ".muserstory.yaml"
+
+// LoadConfig reads the YAML configuration file. When path is empty the file is looked up
+// in $MUSERSTORY_CONFIG, then ./.muserstory.yaml, then <user config dir>/muserstory/config.yaml.
+// A missing file is not an error and yields an empty configuration.
+func LoadConfig(path string) (domain.Config, error) {
+	var config domain.Config
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("MUSERSTORY_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		path = findConfigFile()
+		if path == "" {
+			return config, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func findConfigFile() string {
+	candidates := []string{projectConfigFile}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(configDir, "muserstory", "config.yaml"))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+// ConsoleInteraction writes messages and questions to a terminal and reads the answers
+// line by line. A single goroutine reads the input, so a question that is cancelled leaves
+// its answer to the next one instead of racing it for the input.
+type ConsoleInteraction struct {
+	input *bufio.Reader
+	out   io.Writer
+	lines chan string
+	start sync.Once
+}
+
+// NewConsoleInteraction writes to out and reads answers from in. With a nil in every
+// question fails with ports.ErrInputRequired instead of waiting for an answer.
+func NewConsoleInteraction(in io.Reader, out io.Writer) *ConsoleInteraction {
+	console := &ConsoleInteraction{out: out, lines: make(chan string)}
+	if in != nil {
+		console.input = bufio.NewReader(in)
+	}
+	return console
+}
+
+func (c *ConsoleInteraction) Notify(message string) {
+	fmt.Fprintln(c.out, message)
+}
+
+func (c *ConsoleInteraction) Ask(ctx context.Context, question string) (string, error) {
+	if c.input == nil {
+		return "", fmt.Errorf("%w: %s", ports.ErrInputRequired, strings.TrimRight(strings.TrimSpace(question), ":"))
+	}
+	c.start.Do(func() { go c.readLines() })
+	fmt.Fprint(c.out, question)
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(c.out)
+		return "", ctx.Err()
+	case answer := <-c.lines:
+		return strings.TrimSpace(answer), nil
+	}
+}
+
+// readLines passes the input to Ask line by line. Once the input ends, every question is
+// answered with an empty line.
+func (c *ConsoleInteraction) readLines() {
+	for {
+		line, err := c.input.ReadString('\n')
+		if line != "" || err == nil {
+			c.lines <- line
+		}
+		if err != nil {
+			close(c.lines)
+			return
+		}
+	}
+}
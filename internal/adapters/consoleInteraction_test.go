@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+func TestConsoleInteraction(t *testing.T) {
+	var out strings.Builder
+	console := NewConsoleInteraction(strings.NewReader("  yes \n"), &out)
+	console.Notify("Working...")
+	answer, err := console.Ask(t.Context(), "Continue? ")
+	if err != nil || answer != "yes" {
+		t.Errorf("Ask() = %q, %v, want yes", answer, err)
+	}
+	if got := out.String(); got != "Working...\nContinue? " {
+		t.Errorf("output = %q", got)
+	}
+
+	if _, err := NewConsoleInteraction(nil, io.Discard).Ask(t.Context(), "Continue? "); !errors.Is(err, ports.ErrInputRequired) {
+		t.Errorf("Ask() without input error = %v, want ErrInputRequired", err)
+	}
+
+	answers, writer := io.Pipe()
+	defer writer.Close()
+	piped := NewConsoleInteraction(answers, io.Discard)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := piped.Ask(ctx, "Continue? "); !errors.Is(err, context.Canceled) {
+		t.Errorf("Ask() after cancel error = %v, want context.Canceled", err)
+	}
+	// The line typed after a cancelled question answers the next one.
+	go io.WriteString(writer, "no\n")
+	if answer, err := piped.Ask(t.Context(), "Continue? "); err != nil || answer != "no" {
+		t.Errorf("Ask() after a cancelled question = %q, %v, want no", answer, err)
+	}
+}
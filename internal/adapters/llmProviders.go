@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+const DefaultLLMProvider = "openai"
+
+// LLMProviderFactory creates an LLM service from the provider's configuration.
+type LLMProviderFactory func(cfg domain.LLMProviderConfig) (ports.LLMService, error)
+
+var llmProviders = map[string]LLMProviderFactory{
+	"openai": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		return NewOpenAILLMService(cfg), nil
+	},
+	"azure": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		return NewAzureOpenAILLMService(cfg)
+	},
+	"anthropic": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		return NewAnthropicLLMService(cfg), nil
+	},
+	"ollama": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		baseURL := "http://localhost:11434/v1/"
+		if host := os.Getenv("OLLAMA_HOST"); host != "" {
+			baseURL = strings.TrimRight(host, "/") + "/v1/"
+		}
+		return NewOpenAICompatibleLLMService(cfg, baseURL, "llama3.1"), nil
+	},
+	"llamacpp": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		return NewOpenAICompatibleLLMService(cfg, "http://localhost:8080/v1/", "default"), nil
+	},
+	"offline": func(cfg domain.LLMProviderConfig) (ports.LLMService, error) {
+		return NewOfflineLLMService(cfg)
+	},
+}
+
+// RegisterLLMProvider makes a provider available to NewLLMService under name,
+// replacing any provider previously registered with that name.
+func RegisterLLMProvider(name string, factory LLMProviderFactory) {
+	llmProviders[strings.ToLower(name)] = factory
+}
+
+// LLMProviderNames returns the registered provider names in alphabetical order.
+func LLMProviderNames() []string {
+	names := make([]string, 0, len(llmProviders))
+	for name := range llmProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveLLMProvider picks the provider name: the flag value wins over the
+// MUSERSTORY_LLM_PROVIDER environment variable, which wins over the config file.
+func ResolveLLMProvider(flagValue string, config domain.LLMConfig) string {
+	for _, name := range []string{flagValue, os.Getenv("MUSERSTORY_LLM_PROVIDER"), config.Provider} {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			return name
+		}
+	}
+	return DefaultLLMProvider
+}
+
+// modelNameOf returns the concrete model service uses for modelType, or an empty string
+// when the service does not tell.
+func modelNameOf(service ports.LLMService, modelType domain.ModelType) string {
+	if namer, ok := service.(ports.ModelNamer); ok {
+		return namer.ModelName(modelType)
+	}
+	return ""
+}
+
+// NewLLMService creates the LLM service of the named provider using its settings from config.
+// Failed requests are retried and spread over the provider's request budget.
+func NewLLMService(name string, config domain.LLMConfig) (ports.LLMService, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	factory, ok := llmProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider '%s', available providers are: %s", name, strings.Join(LLMProviderNames(), ", "))
+	}
+	providerConfig := config.Providers[name]
+	service, err := factory(providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create LLM provider '%s': %w", name, err)
+	}
+	maxRetries := DefaultMaxRetries
+	if providerConfig.MaxRetries != nil {
+		maxRetries = *providerConfig.MaxRetries
+	}
+	return NewRetryingLLMService(service, maxRetries, providerConfig.RequestsPerMinute), nil
+}
@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+// unknownModel is used for responses whose provider did not report a model.
+const unknownModel = "unknown"
+
+// MeteredLLMService adds up the token usage of successful responses of the wrapped
+// service per model. Placed in front of the cache it only counts requests that reached
+// the provider.
+type MeteredLLMService struct {
+	inner ports.LLMService
+	mu    sync.Mutex
+	usage map[string]*domain.ModelUsage
+}
+
+func NewMeteredLLMService(inner ports.LLMService) *MeteredLLMService {
+	return &MeteredLLMService{inner: inner, usage: make(map[string]*domain.ModelUsage)}
+}
+
+func (s *MeteredLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	return s.record(s.inner.AskSimple(ctx, input))
+}
+
+func (s *MeteredLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	return s.record(s.inner.AskAdvanced(ctx, input))
+}
+
+func (s *MeteredLLMService) ModelName(modelType domain.ModelType) string {
+	return modelNameOf(s.inner, modelType)
+}
+
+func (s *MeteredLLMService) record(response domain.LLMResponse, err error) (domain.LLMResponse, error) {
+	if err != nil {
+		return response, err
+	}
+	model := response.Usage.Model
+	if model == "" {
+		model = unknownModel
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage, ok := s.usage[model]
+	if !ok {
+		usage = &domain.ModelUsage{Model: model}
+		s.usage[model] = usage
+	}
+	usage.Requests++
+	usage.PromptTokens += response.Usage.PromptTokens
+	usage.CompletionTokens += response.Usage.CompletionTokens
+	return response, nil
+}
+
+// Usage returns the usage so far per model, ordered by model name.
+func (s *MeteredLLMService) Usage() []domain.ModelUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := make([]domain.ModelUsage, 0, len(s.usage))
+	for _, modelUsage := range s.usage {
+		usage = append(usage, *modelUsage)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].Model < usage[j].Model
+	})
+	return usage
+}
@@ -0,0 +1,60 @@
+package adapters
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+func TestMeteredLLMService(t *testing.T) {
+	ctx := t.Context()
+	meter := NewMeteredLLMService(&countingLLMService{})
+	cache := NewCachingLLMService(meter, t.TempDir(), "counting", time.Hour, 0)
+
+	input := domain.LLMSimpleInput{UserMessage: "story", ModelType: domain.ModelTypeSimple}
+	cache.AskSimple(ctx, input)
+	// Cache hits never reach the meter.
+	cache.AskSimple(ctx, input)
+	cache.AskAdvanced(ctx, domain.LLMAdvancedInput{UserMessage: "story", SchemaName: "A"})
+
+	usage := meter.Usage()
+	if len(usage) != 1 || usage[0] != (domain.ModelUsage{Model: "counting", Requests: 2, PromptTokens: 20}) {
+		t.Errorf("Usage() = %+v", usage)
+	}
+
+	failing := NewMeteredLLMService(&flakyLLMService{errs: []error{newHTTPError(http.StatusBadGateway, nil, errors.New("down"))}})
+	failing.AskSimple(ctx, input)
+	failing.AskSimple(ctx, input)
+	if usage := failing.Usage(); len(usage) != 1 || usage[0].Model != unknownModel || usage[0].Requests != 1 {
+		t.Errorf("Usage() after a failure = %+v, want one request of an unknown model", usage)
+	}
+}
+
+func TestUsageLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.jsonl")
+	if records, err := ReadUsageLog(path); err != nil || records != nil {
+		t.Fatalf("ReadUsageLog() of a missing log = %v, %v", records, err)
+	}
+
+	at := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	first := domain.UsageRecord{Time: at, Command: "categorize", Provider: "openai", Model: "gpt-4o-mini", Requests: 3, PromptTokens: 300, CompletionTokens: 9, Cost: 0.01}
+	second := domain.UsageRecord{Time: at.Add(time.Minute), Command: "summarize", Provider: "openai", Model: "gpt-4o-mini", Requests: 1}
+	if err := AppendUsageLog(path, []domain.UsageRecord{first}); err != nil {
+		t.Fatalf("AppendUsageLog() error = %v", err)
+	}
+	if err := AppendUsageLog(path, []domain.UsageRecord{second}); err != nil {
+		t.Fatalf("AppendUsageLog() error = %v", err)
+	}
+
+	records, err := ReadUsageLog(path)
+	if err != nil {
+		t.Fatalf("ReadUsageLog() error = %v", err)
+	}
+	if len(records) != 2 || records[0] != first || records[1] != second {
+		t.Errorf("ReadUsageLog() = %+v", records)
+	}
+}
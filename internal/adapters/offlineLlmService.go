@@ -0,0 +1,255 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// OfflineLLMService answers without network access, for tests and air-gapped use.
+// Responses come from a fixtures file keyed by domain.PromptHash when it has a match,
+// and otherwise from deterministic rules chosen by the request name and schema, so that
+// they do not depend on the wording of the prompts.
+type OfflineLLMService struct {
+	fixtures map[string]string
+}
+
+// NewOfflineLLMService creates the offline service. The fixtures file is taken from the
+// provider config or MUSERSTORY_OFFLINE_FIXTURES and is optional.
+func NewOfflineLLMService(cfg domain.LLMProviderConfig) (*OfflineLLMService, error) {
+	service := &OfflineLLMService{fixtures: make(map[string]string)}
+
+	path := cfg.Fixtures
+	if path == "" {
+		path = os.Getenv("MUSERSTORY_OFFLINE_FIXTURES")
+	}
+	if path == "" {
+		return service, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading offline fixtures %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &service.fixtures); err != nil {
+		return nil, fmt.Errorf("error parsing offline fixtures %s: %w", path, err)
+	}
+	return service, nil
+}
+
+// AddFixture makes the service answer the request identified by hash with response.
+func (s *OfflineLLMService) AddFixture(hash string, response string) {
+	s.fixtures[hash] = response
+}
+
+// offlineModel is the model name reported in the usage of offline responses.
+const offlineModel = "offline"
+
+func (s *OfflineLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	return offlineResponse(s.answerSimple(input))
+}
+
+func (s *OfflineLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	return offlineResponse(s.answerAdvanced(input))
+}
+
+func offlineResponse(content string, err error) (domain.LLMResponse, error) {
+	if err != nil {
+		return domain.LLMResponse{}, err
+	}
+	return domain.LLMResponse{Content: content, Usage: domain.LLMUsage{Model: offlineModel}}, nil
+}
+
+func (s *OfflineLLMService) answerSimple(input domain.LLMSimpleInput) (string, error) {
+	if response, ok := s.fixtures[domain.PromptHash(input.ModelType, input.SystemMessage, input.UserMessage, "")]; ok {
+		return response, nil
+	}
+
+	switch input.Name {
+	case "CategorizeUserStory":
+		return categorizeByKeywords(input.UserMessage, nil), nil
+	case "SummarizeUserStories":
+		return offlineSummary(input.UserMessage), nil
+	}
+	return "Offline response.", nil
+}
+
+func (s *OfflineLLMService) answerAdvanced(input domain.LLMAdvancedInput) (string, error) {
+	if response, ok := s.fixtures[domain.PromptHash(input.ModelType, input.SystemMessage, input.UserMessage, input.SchemaName)]; ok {
+		return response, nil
+	}
+
+	schema, err := parseSchema(input.Schema)
+	if err != nil {
+		return "", err
+	}
+
+	var response interface{}
+	switch input.SchemaName {
+	case "GenerateNewUserStories":
+		count := 1
+		if items, ok := schemaProperty(schema, "new_user_stories")["maxItems"].(float64); ok && items > 0 {
+			count = int(items)
+		}
+		stories := make([]string, count)
+		for i := range stories {
+			stories[i] = fmt.Sprintf("As a user, I want offline generated feature %d so that I can try the workflow without a network.", i+1)
+		}
+		response = map[string]interface{}{"new_user_stories": stories}
+	case "CategorizeUserStory":
+		response = map[string]string{"category": categorizeByKeywords(input.UserMessage, schemaEnum(schema, "category"))}
+	case "CategorizeUserStories":
+		possible := schemaEnum(schema, "category")
+		var categories []map[string]string
+		for _, line := range strings.Split(input.UserMessage, "\n") {
+			id, description, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "- ID "), ": ")
+			if !ok {
+				continue
+			}
+			categories = append(categories, map[string]string{"id": id, "category": categorizeByKeywords(description, possible)})
+		}
+		response = map[string]interface{}{"categories": categories}
+	case "DraftAcceptanceCriteria":
+		response = map[string]interface{}{"criteria": []string{
+			"Given the feature is available\nWhen a user follows the story\nThen the described outcome is reached",
+			"The outcome is shown to the user without reloading the page",
+		}}
+	case "PrioritizeUserStories":
+		// The offline ranking keeps the given order, cycling through the allowed priorities,
+		// if the schema lists any.
+		priorities := schemaEnum(schema, "priority")
+		var ranking []map[string]string
+		for _, line := range strings.Split(input.UserMessage, "\n") {
+			id, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "- ID "), ": ")
+			if !ok {
+				continue
+			}
+			priority := ""
+			if len(priorities) > 0 {
+				priority = priorities[len(ranking)%len(priorities)]
+			}
+			ranking = append(ranking, map[string]string{"id": id, "priority": priority, "rationale": "Offline ranking keeps the file order."})
+		}
+		response = map[string]interface{}{"ranking": ranking}
+	case "GeneratePossibleCategories":
+		response = map[string]interface{}{"categories": []string{"Bug", "Feature", "Chore", "Technical Debt"}}
+	default:
+		response = zeroValue(schema)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal offline response: %w", err)
+	}
+	return string(data), nil
+}
+
+// categorizeByKeywords picks the first of the possible categories mentioned in the story,
+// and otherwise falls back to a few fixed keyword rules.
+func categorizeByKeywords(storyText string, possible []string) string {
+	lowerText := strings.ToLower(storyText)
+	for _, category := range possible {
+		if category != "" && strings.Contains(lowerText, strings.ToLower(category)) {
+			return category
+		}
+	}
+	if strings.Contains(lowerText, "bug") || strings.Contains(lowerText, "fix") {
+		return "Bug"
+	}
+	if strings.Contains(lowerText, "feature") || strings.Contains(lowerText, "i want") {
+		return "Feature"
+	}
+	if len(storyText)%3 == 0 {
+		return "Chore"
+	}
+	return "Technical Debt"
+}
+
+// schemaEnum returns the allowed values of the first property called name in schema.
+func schemaEnum(schema map[string]interface{}, name string) []string {
+	enum, _ := schemaProperty(schema, name)["enum"].([]interface{})
+	var values []string
+	for _, value := range enum {
+		if text, ok := value.(string); ok {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+// schemaProperty finds the first property called name in schema, searching nested
+// objects and array items. It returns nil if there is none.
+func schemaProperty(schema map[string]interface{}, name string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if property, ok := properties[name].(map[string]interface{}); ok {
+		return property
+	}
+	for _, property := range properties {
+		if nested, ok := property.(map[string]interface{}); ok {
+			if found := schemaProperty(nested, name); found != nil {
+				return found
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		return schemaProperty(items, name)
+	}
+	return nil
+}
+
+func offlineSummary(stories string) string {
+	var descriptions []string
+	for _, line := range strings.Split(stories, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			descriptions = append(descriptions, line)
+		}
+	}
+	if len(descriptions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("This project is described by %d user stories, starting with: %s", len(descriptions), descriptions[0])
+}
+
+// parseSchema turns a schema, such as one made by domain.GenerateSchema, into its JSON form.
+func parseSchema(schema interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	return parsed, nil
+}
+
+// zeroValue builds the smallest JSON value that satisfies a JSON schema: objects with
+// all their properties, empty arrays, empty strings, zeros and false.
+func zeroValue(schema map[string]interface{}) interface{} {
+	switch schema["type"] {
+	case "object":
+		object := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			propertySchema, _ := property.(map[string]interface{})
+			object[name] = zeroValue(propertySchema)
+		}
+		return object
+	case "array":
+		return []interface{}{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return nil
+}
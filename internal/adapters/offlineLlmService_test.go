@@ -0,0 +1,57 @@
+package adapters
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+func TestOfflineLLMServiceFixtures(t *testing.T) {
+	ctx := t.Context()
+	hash := domain.PromptHash(domain.ModelTypeSimple, "Categorize this.", "A story", "")
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(path, []byte(`{"`+hash+`": "From fixture"}`), 0644); err != nil {
+		t.Fatalf("failed to write fixtures: %v", err)
+	}
+
+	service, err := NewOfflineLLMService(domain.LLMProviderConfig{Fixtures: path})
+	if err != nil {
+		t.Fatalf("NewOfflineLLMService() error = %v", err)
+	}
+	got, err := service.AskSimple(ctx, domain.LLMSimpleInput{SystemMessage: "Categorize this.", UserMessage: "A story", ModelType: domain.ModelTypeSimple})
+	if err != nil || got.Content != "From fixture" {
+		t.Errorf("AskSimple() = %q, %v, want the fixture response", got.Content, err)
+	}
+	got, _ = service.AskSimple(ctx, domain.LLMSimpleInput{SystemMessage: "Categorize this.", UserMessage: "Fix the login bug", ModelType: domain.ModelTypeSimple, Name: "CategorizeUserStory"})
+	if got.Content != "Bug" {
+		t.Errorf("AskSimple() without fixture = %q, want Bug", got.Content)
+	}
+
+	if _, err := NewOfflineLLMService(domain.LLMProviderConfig{Fixtures: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("expected an error for a missing fixtures file")
+	}
+}
+
+func TestOfflineLLMServiceSchemaFallback(t *testing.T) {
+	ctx := t.Context()
+	type response struct {
+		Names []string `json:"names"`
+		Count int      `json:"count"`
+		Label string   `json:"label" jsonschema:"enum=first,enum=second"`
+	}
+	service, _ := NewOfflineLLMService(domain.LLMProviderConfig{})
+	raw, err := service.AskAdvanced(ctx, domain.LLMAdvancedInput{SchemaName: "Unknown", Schema: domain.GenerateSchema[response]()})
+	if err != nil {
+		t.Fatalf("AskAdvanced() error = %v", err)
+	}
+	var got response
+	if err := json.Unmarshal([]byte(raw.Content), &got); err != nil {
+		t.Fatalf("response %s is not valid for the schema: %v", raw.Content, err)
+	}
+	if got.Names == nil || got.Label != "first" {
+		t.Errorf("AskAdvanced() = %s", raw.Content)
+	}
+}
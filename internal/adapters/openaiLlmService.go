@@ -2,96 +2,182 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"strings"
 
 	"github.com/morgansundqvist/muserstory/internal/domain"
 	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
 )
 
-type OpenAILLMService struct {
+const defaultAzureAPIVersion = "2024-10-21"
+
+var openAIDefaultModels = map[domain.ModelType]string{
+	domain.ModelTypeSimple:            openai.ChatModelGPT4oMini,
+	domain.ModelTypeAdvanced:          openai.ChatModelGPT4o,
+	domain.ModelTypeReasoningSimple:   openai.ChatModelO3Mini,
+	domain.ModelTypeReasoningAdvanced: openai.ChatModelO1,
 }
 
-func NewOpenAILLMService() *OpenAILLMService {
-	return &OpenAILLMService{}
+// OpenAILLMService talks to the OpenAI chat completions API, or to any server that
+// implements it, such as Azure OpenAI, Ollama or the llama.cpp server.
+type OpenAILLMService struct {
+	client        openai.Client
+	config        domain.LLMProviderConfig
+	defaultModels map[domain.ModelType]string
+	// legacyMaxTokens sends max_tokens instead of max_completion_tokens, which
+	// OpenAI-compatible servers do not all understand yet.
+	legacyMaxTokens bool
+	// azureEndpoint is set for Azure OpenAI, where requests are routed to a deployment
+	// named after the model instead of passing the model in the request body.
+	azureEndpoint string
 }
 
-func (s *OpenAILLMService) CategorizeStory(storyText string) (string, error) {
-	fmt.Printf("MockLLMService: Attempting to categorize story: \"%s\"\n", storyText)
-	if strings.Contains(strings.ToLower(storyText), "bug") || strings.Contains(strings.ToLower(storyText), "fix") {
-		return "Bug", nil
+func NewOpenAILLMService(cfg domain.LLMProviderConfig) *OpenAILLMService {
+	// Retries are handled by RetryingLLMService for all providers alike.
+	opts := []option.RequestOption{option.WithMaxRetries(0)}
+	if cfg.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(cfg.APIKey))
 	}
-	if strings.Contains(strings.ToLower(storyText), "feature") || strings.Contains(strings.ToLower(storyText), "i want") {
-		return "Feature", nil
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
 	}
-	if len(storyText)%3 == 0 { 
-		return "Chore", nil
+	return &OpenAILLMService{
+		client:        openai.NewClient(opts...),
+		config:        cfg,
+		defaultModels: openAIDefaultModels,
 	}
-	return "Technical Debt", nil 
 }
 
-func (s *OpenAILLMService) CategorizeStories(stories []domain.UserStory) ([]domain.UserStory, error) {
-	fmt.Printf("MockLLMService: Attempting to categorize %d stories.\n", len(stories))
-	updatedStories := make([]domain.UserStory, len(stories))
-	for i, story := range stories {
-		category, err := s.CategorizeStory(story.Description)
-		if err != nil {
-			fmt.Printf("Error categorizing story ID %s ('%s'): %v. Assigning 'Uncategorized'.\n", story.ID, story.Description, err)
-			updatedStories[i] = story
-			updatedStories[i].Category = "Uncategorized"
-			continue
-		}
-		updatedStories[i] = story
-		updatedStories[i].Category = category
+// NewOpenAICompatibleLLMService creates a service for a local server exposing the OpenAI
+// API. All model types default to defaultModel, and defaultBaseURL is used unless configured.
+func NewOpenAICompatibleLLMService(cfg domain.LLMProviderConfig, defaultBaseURL string, defaultModel string) *OpenAILLMService {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		// Local servers ignore the key, but the client always sends one.
+		apiKey = "local"
+	}
+	return &OpenAILLMService{
+		client: openai.NewClient(option.WithBaseURL(baseURL), option.WithAPIKey(apiKey), option.WithMaxRetries(0)),
+		config: cfg,
+		defaultModels: map[domain.ModelType]string{
+			domain.ModelTypeSimple:            defaultModel,
+			domain.ModelTypeAdvanced:          defaultModel,
+			domain.ModelTypeReasoningSimple:   defaultModel,
+			domain.ModelTypeReasoningAdvanced: defaultModel,
+		},
+		legacyMaxTokens: true,
 	}
-	return updatedStories, nil
 }
 
-func (s *OpenAILLMService) AskSimple(input domain.LLMSimpleInput) (string, error) {
-	client := openai.NewClient()
+// NewAzureOpenAILLMService creates a service for Azure OpenAI. The endpoint and key default
+// to AZURE_OPENAI_ENDPOINT and AZURE_OPENAI_API_KEY; deployments are expected to be named
+// after the OpenAI models unless the model settings name them.
+func NewAzureOpenAILLMService(cfg domain.LLMProviderConfig) (*OpenAILLMService, error) {
+	endpoint := cfg.BaseURL
+	if endpoint == "" {
+		endpoint = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("azure provider needs an endpoint, set base_url or AZURE_OPENAI_ENDPOINT")
+	}
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	}
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	client := openai.NewClient(
+		option.WithHeaderDel("authorization"),
+		option.WithHeader("Api-Key", apiKey),
+		option.WithQuery("api-version", apiVersion),
+		option.WithMaxRetries(0),
+	)
+	return &OpenAILLMService{
+		client:        client,
+		config:        cfg,
+		defaultModels: openAIDefaultModels,
+		azureEndpoint: strings.TrimRight(endpoint, "/"),
+	}, nil
+}
 
-	model := openai.ChatModelGPT4o
+// requestOptions returns the per-request options for model.
+func (s *OpenAILLMService) requestOptions(model string) []option.RequestOption {
+	if s.azureEndpoint == "" {
+		return nil
+	}
+	return []option.RequestOption{
+		option.WithBaseURL(s.azureEndpoint + "/openai/deployments/" + model + "/"),
+	}
+}
 
-	if input.ModelType == domain.ModelTypeSimple {
-		model = openai.ChatModelGPT4oMini
-	} else if input.ModelType == domain.ModelTypeAdvanced {
-		model = openai.ChatModelGPT4o
-	} else if input.ModelType == domain.ModelTypeReasoningSimple {
-		model = openai.ChatModelO3Mini
-	} else if input.ModelType == domain.ModelTypeReasoningAdvanced {
-		model = openai.ChatModelO1
+func (s *OpenAILLMService) modelSettings(modelType domain.ModelType) domain.ModelSettings {
+	defaultModel, ok := s.defaultModels[modelType]
+	if !ok {
+		defaultModel = s.defaultModels[domain.ModelTypeAdvanced]
 	}
+	return s.config.ModelSettingsFor(modelType, defaultModel)
+}
 
-	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+func (s *OpenAILLMService) ModelName(modelType domain.ModelType) string {
+	return s.modelSettings(modelType).Model
+}
+
+// newParams builds the request parameters shared by AskSimple and AskAdvanced.
+func (s *OpenAILLMService) newParams(settings domain.ModelSettings, systemMessage string, userMessage string) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(input.SystemMessage),
-			openai.UserMessage(input.UserMessage),
+			openai.SystemMessage(systemMessage),
+			openai.UserMessage(userMessage),
 		},
-		Model: model,
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to get chat completion: %w", err)
+		Model: settings.Model,
 	}
-	println(chatCompletion.Choices[0].Message.Content)
-
-	return chatCompletion.Choices[0].Message.Content, nil
+	if settings.Temperature != nil {
+		params.Temperature = openai.Float(*settings.Temperature)
+	}
+	if settings.MaxTokens > 0 {
+		if s.legacyMaxTokens {
+			params.MaxTokens = openai.Int(int64(settings.MaxTokens))
+		} else {
+			params.MaxCompletionTokens = openai.Int(int64(settings.MaxTokens))
+		}
+	}
+	return params
 }
 
-func (s *OpenAILLMService) AskAdvanced(input domain.LLMAdvancedInput) (string, error) {
-
-	client := openai.NewClient()
+// requestContext applies the model timeout, if any.
+func requestContext(ctx context.Context, settings domain.ModelSettings) (context.Context, context.CancelFunc) {
+	if settings.Timeout > 0 {
+		return context.WithTimeout(ctx, settings.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
 
-	model := openai.ChatModelGPT4o
+func (s *OpenAILLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	settings := s.modelSettings(input.ModelType)
+	requestCtx, cancel := requestContext(ctx, settings)
+	defer cancel()
 
-	if input.ModelType == domain.ModelTypeSimple {
-		model = openai.ChatModelGPT4oMini
-	} else if input.ModelType == domain.ModelTypeAdvanced {
-		model = openai.ChatModelGPT4o
-	} else if input.ModelType == domain.ModelTypeReasoningSimple {
-		model = openai.ChatModelO3Mini
-	} else if input.ModelType == domain.ModelTypeReasoningAdvanced {
-		model = openai.ChatModelO1
+	chatCompletion, err := s.client.Chat.Completions.New(requestCtx, s.newParams(settings, input.SystemMessage, input.UserMessage), s.requestOptions(settings.Model)...)
+	if err != nil {
+		return domain.LLMResponse{}, openAIError(ctx, err)
 	}
+	return completionContent(chatCompletion)
+}
+
+func (s *OpenAILLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	settings := s.modelSettings(input.ModelType)
+	requestCtx, cancel := requestContext(ctx, settings)
+	defer cancel()
 
 	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
 		Name:        input.SchemaName,
@@ -100,22 +186,46 @@ func (s *OpenAILLMService) AskAdvanced(input domain.LLMAdvancedInput) (string, e
 		Strict:      openai.Bool(true),
 	}
 
-	chat, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(input.SystemMessage),
-			openai.UserMessage(input.UserMessage),
-		},
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: schemaParam,
-			},
+	params := s.newParams(settings, input.SystemMessage, input.UserMessage)
+	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+			JSONSchema: schemaParam,
 		},
-		Model: model,
-	})
+	}
+
+	chat, err := s.client.Chat.Completions.New(requestCtx, params, s.requestOptions(settings.Model)...)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get chat completion: %w", err)
+		return domain.LLMResponse{}, openAIError(ctx, err)
+	}
+
+	return completionContent(chat)
+}
+
+func completionContent(completion *openai.ChatCompletion) (domain.LLMResponse, error) {
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return domain.LLMResponse{}, &domain.LLMError{Kind: domain.ErrLLMEmptyResponse}
 	}
+	return domain.LLMResponse{
+		Content: completion.Choices[0].Message.Content,
+		Usage: domain.LLMUsage{
+			Model:            completion.Model,
+			PromptTokens:     completion.Usage.PromptTokens,
+			CompletionTokens: completion.Usage.CompletionTokens,
+		},
+	}, nil
+}
 
-	return chat.Choices[0].Message.Content, nil
+// openAIError classifies an error of the OpenAI client by its HTTP status.
+func openAIError(ctx context.Context, err error) error {
+	err = fmt.Errorf("failed to get chat completion: %w", err)
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return newTransportError(ctx, err)
+	}
+	var header http.Header
+	if apiErr.Response != nil {
+		header = apiErr.Response.Header
+	}
+	return newHTTPError(apiErr.StatusCode, header, err)
 }
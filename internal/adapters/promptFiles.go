@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// LoadPromptDir reads the prompt templates in dir, one <prompt name>.tmpl file per prompt.
+// Prompts without a file keep their default; files that name no prompt are an error, so
+// that a misspelled file name does not go unnoticed.
+func LoadPromptDir(dir string) (map[domain.PromptName]domain.PromptTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prompts directory %s: %w", dir, err)
+	}
+	templates := make(map[domain.PromptName]domain.PromptTemplate)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		name, err := domain.ParsePromptName(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("prompts directory %s: %w", dir, err)
+		}
+		path := filepath.Join(dir, entry.Name())
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading prompt %s: %w", path, err)
+		}
+		templates[name] = domain.PromptTemplate{Name: name, Source: path, Text: string(text)}
+	}
+	return templates, nil
+}
+
+// WritePromptDir writes templates to dir as files LoadPromptDir reads. Existing files are
+// only replaced when overwrite is set.
+func WritePromptDir(dir string, templates []domain.PromptTemplate, overwrite bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating prompts directory %s: %w", dir, err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	var written []string
+	for _, prompt := range templates {
+		path := filepath.Join(dir, prompt.Name.FileName())
+		file, err := os.OpenFile(path, flags, 0644)
+		if errors.Is(err, os.ErrExist) {
+			return written, fmt.Errorf("prompt file %s already exists, use --force to replace it", path)
+		}
+		if err != nil {
+			return written, fmt.Errorf("error creating prompt file %s: %w", path, err)
+		}
+		text := prompt.Text
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		_, err = file.WriteString(text)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return written, fmt.Errorf("error writing prompt file %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+func TestPromptDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "prompts")
+	templates := []domain.PromptTemplate{
+		{Name: domain.PromptSummarize, Text: "Summarize briefly."},
+		{Name: domain.PromptGenerate, Text: "Generate {{.Count}} stories.\n"},
+	}
+	if _, err := WritePromptDir(dir, templates, false); err != nil {
+		t.Fatalf("WritePromptDir() error = %v", err)
+	}
+	if _, err := WritePromptDir(dir, templates, false); err == nil {
+		t.Error("WritePromptDir() replaced existing files without overwrite")
+	}
+	if _, err := WritePromptDir(dir, templates, true); err != nil {
+		t.Fatalf("WritePromptDir() with overwrite error = %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("notes"), 0644)
+
+	loaded, err := LoadPromptDir(dir)
+	if err != nil {
+		t.Fatalf("LoadPromptDir() error = %v", err)
+	}
+	if len(loaded) != 2 || loaded[domain.PromptSummarize].Text != "Summarize briefly.\n" ||
+		loaded[domain.PromptGenerate].Source != filepath.Join(dir, "generate.tmpl") {
+		t.Errorf("LoadPromptDir() = %+v", loaded)
+	}
+
+	os.WriteFile(filepath.Join(dir, "sumarize.tmpl"), []byte("typo"), 0644)
+	if _, err := LoadPromptDir(dir); err == nil {
+		t.Error("LoadPromptDir() accepted a file that names no prompt")
+	}
+}
@@ -0,0 +1,179 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryingLLMService repeats requests of the wrapped service that fail with a rate limit
+// or a server error, waiting with exponential backoff and jitter, or as long as the server
+// asked with Retry-After. It can also spread requests to stay within a per-minute budget.
+type RetryingLLMService struct {
+	inner      ports.LLMService
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	// interval is the minimum time between the start of two requests, zero for no budget.
+	interval time.Duration
+	sleep    func(context.Context, time.Duration) error
+	now      func() time.Time
+
+	mu          sync.Mutex
+	nextRequest time.Time
+}
+
+// NewRetryingLLMService wraps inner. requestsPerMinute of zero or less sets no budget.
+func NewRetryingLLMService(inner ports.LLMService, maxRetries int, requestsPerMinute int) *RetryingLLMService {
+	service := &RetryingLLMService{
+		inner:      inner,
+		maxRetries: maxRetries,
+		baseDelay:  defaultRetryBaseDelay,
+		maxDelay:   defaultRetryMaxDelay,
+		sleep:      sleepContext,
+		now:        time.Now,
+	}
+	if requestsPerMinute > 0 {
+		service.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return service
+}
+
+func (s *RetryingLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	return s.do(ctx, func() (domain.LLMResponse, error) {
+		return s.inner.AskSimple(ctx, input)
+	})
+}
+
+func (s *RetryingLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	return s.do(ctx, func() (domain.LLMResponse, error) {
+		return s.inner.AskAdvanced(ctx, input)
+	})
+}
+
+func (s *RetryingLLMService) ModelName(modelType domain.ModelType) string {
+	return modelNameOf(s.inner, modelType)
+}
+
+// do runs ask until it succeeds, fails for good or ctx is done.
+func (s *RetryingLLMService) do(ctx context.Context, ask func() (domain.LLMResponse, error)) (domain.LLMResponse, error) {
+	for attempt := 0; ; attempt++ {
+		if err := s.waitForBudget(ctx); err != nil {
+			return domain.LLMResponse{}, err
+		}
+		response, err := ask()
+		if err == nil || attempt >= s.maxRetries || !domain.IsRetryableLLMError(err) {
+			return response, err
+		}
+		if err := s.sleep(ctx, s.retryDelay(err, attempt)); err != nil {
+			return domain.LLMResponse{}, err
+		}
+	}
+}
+
+// waitForBudget blocks until the next request fits in the per-minute budget.
+func (s *RetryingLLMService) waitForBudget(ctx context.Context) error {
+	if s.interval == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	now := s.now()
+	start := s.nextRequest
+	if start.Before(now) {
+		start = now
+	}
+	s.nextRequest = start.Add(s.interval)
+	s.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		return s.sleep(ctx, wait)
+	}
+	return nil
+}
+
+// sleepContext waits for d, returning early with the context's error when it is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryDelay honours Retry-After and otherwise backs off exponentially with full jitter.
+func (s *RetryingLLMService) retryDelay(err error, attempt int) time.Duration {
+	var llmErr *domain.LLMError
+	if errors.As(err, &llmErr) && llmErr.RetryAfter > 0 {
+		return llmErr.RetryAfter
+	}
+	backoff := s.baseDelay << attempt
+	if backoff <= 0 || backoff > s.maxDelay {
+		backoff = s.maxDelay
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// newHTTPError classifies a failed HTTP response of an LLM API.
+func newHTTPError(statusCode int, header http.Header, err error) error {
+	llmErr := &domain.LLMError{StatusCode: statusCode, RetryAfter: parseRetryAfter(header), Err: err}
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		llmErr.Kind = domain.ErrLLMRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		llmErr.Kind = domain.ErrLLMAuthFailed
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		llmErr.Kind = domain.ErrLLMUnavailable
+	default:
+		return err
+	}
+	return llmErr
+}
+
+// newTransportError classifies a request that got no response at all. It is final once
+// the caller's ctx has ended; network failures and requests that only ran past their
+// own model timeout are worth retrying.
+func newTransportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return &domain.LLMError{Kind: domain.ErrLLMUnavailable, Err: err}
+}
+
+// parseRetryAfter reads retry-after-ms or Retry-After, in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+type flakyLLMService struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return domain.LLMResponse{}, f.errs[f.calls-1]
+	}
+	return domain.LLMResponse{Content: "ok"}, nil
+}
+
+func (f *flakyLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	return f.AskSimple(ctx, domain.LLMSimpleInput{})
+}
+
+func newTestRetryingService(inner *flakyLLMService, maxRetries int, requestsPerMinute int) (*RetryingLLMService, *[]time.Duration) {
+	var sleeps []time.Duration
+	service := NewRetryingLLMService(inner, maxRetries, requestsPerMinute)
+	now := time.Unix(0, 0)
+	service.now = func() time.Time { return now }
+	service.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return ctx.Err()
+	}
+	return service, &sleeps
+}
+
+func TestRetryingLLMServiceRetries(t *testing.T) {
+	rateLimited := newHTTPError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"7"}}, errors.New("slow down"))
+	unavailable := newHTTPError(http.StatusBadGateway, nil, errors.New("bad gateway"))
+	inner := &flakyLLMService{errs: []error{rateLimited, unavailable}}
+	service, sleeps := newTestRetryingService(inner, 3, 0)
+
+	got, err := service.AskSimple(t.Context(), domain.LLMSimpleInput{})
+	if err != nil || got.Content != "ok" {
+		t.Fatalf("AskSimple() = %q, %v", got.Content, err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("inner called %d times, want 3", inner.calls)
+	}
+	if len(*sleeps) != 2 || (*sleeps)[0] != 7*time.Second {
+		t.Errorf("sleeps = %v, want Retry-After first", *sleeps)
+	}
+	if backoff := (*sleeps)[1]; backoff < defaultRetryBaseDelay || backoff > 2*defaultRetryBaseDelay {
+		t.Errorf("second backoff = %v, want between 1s and 2s", backoff)
+	}
+}
+
+func TestRetryingLLMServiceGivesUp(t *testing.T) {
+	rateLimited := newHTTPError(http.StatusTooManyRequests, nil, errors.New("slow down"))
+	inner := &flakyLLMService{errs: []error{rateLimited, rateLimited, rateLimited}}
+	service, _ := newTestRetryingService(inner, 2, 0)
+	if _, err := service.AskSimple(t.Context(), domain.LLMSimpleInput{}); !errors.Is(err, domain.ErrLLMRateLimited) {
+		t.Errorf("AskSimple() error = %v, want rate limited", err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("inner called %d times, want 3", inner.calls)
+	}
+
+	authFailed := newHTTPError(http.StatusUnauthorized, nil, errors.New("bad key"))
+	inner = &flakyLLMService{errs: []error{authFailed}}
+	service, _ = newTestRetryingService(inner, 2, 0)
+	if _, err := service.AskSimple(t.Context(), domain.LLMSimpleInput{}); !errors.Is(err, domain.ErrLLMAuthFailed) {
+		t.Errorf("AskSimple() error = %v, want auth failed", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("auth failure was retried, inner called %d times", inner.calls)
+	}
+}
+
+func TestRetryingLLMServiceBudget(t *testing.T) {
+	inner := &flakyLLMService{}
+	service, sleeps := newTestRetryingService(inner, 0, 30)
+	for i := 0; i < 3; i++ {
+		service.AskAdvanced(t.Context(), domain.LLMAdvancedInput{})
+	}
+	if fmt.Sprint(*sleeps) != "[2s 2s]" {
+		t.Errorf("sleeps = %v, want two 2s waits", *sleeps)
+	}
+}
+
+func TestRetryingLLMServiceStopsWhenCancelled(t *testing.T) {
+	unavailable := newHTTPError(http.StatusServiceUnavailable, nil, errors.New("down"))
+	inner := &flakyLLMService{errs: []error{unavailable, unavailable}}
+	service := NewRetryingLLMService(inner, 3, 0)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := service.AskSimple(ctx, domain.LLMSimpleInput{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("AskSimple() error = %v, want context.Canceled", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner called %d times after cancellation, want 1", inner.calls)
+	}
+}
+
+func TestModelTimeoutIsRetried(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, `{"model":"test","content":[{"type":"text","text":"ok"}]}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	anthropic := NewAnthropicLLMService(domain.LLMProviderConfig{
+		APIKey:  "test",
+		BaseURL: server.URL,
+		Models:  map[string]domain.ModelSettings{"simple": {Timeout: 20 * time.Millisecond}},
+	})
+	input := domain.LLMSimpleInput{ModelType: domain.ModelTypeSimple}
+
+	_, err := anthropic.AskSimple(t.Context(), input)
+	if !errors.Is(err, domain.ErrLLMUnavailable) || !domain.IsRetryableLLMError(err) {
+		t.Fatalf("AskSimple() error = %v, want a retryable ErrLLMUnavailable", err)
+	}
+
+	service := NewRetryingLLMService(anthropic, 1, 0)
+	service.sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	requests.Store(0)
+	response, err := service.AskSimple(t.Context(), input)
+	if err != nil || response.Content != "ok" {
+		t.Fatalf("AskSimple() = %q, %v; want the retried answer", response.Content, err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := anthropic.AskSimple(ctx, input); !errors.Is(err, context.Canceled) || domain.IsRetryableLLMError(err) {
+		t.Errorf("AskSimple() with a cancelled ctx error = %v, want a final context.Canceled", err)
+	}
+}
+
+func TestNewHTTPError(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   error
+	}{
+		{http.StatusTooManyRequests, domain.ErrLLMRateLimited},
+		{http.StatusUnauthorized, domain.ErrLLMAuthFailed},
+		{http.StatusForbidden, domain.ErrLLMAuthFailed},
+		{http.StatusServiceUnavailable, domain.ErrLLMUnavailable},
+		{529, domain.ErrLLMUnavailable},
+		{http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		cause := errors.New("cause")
+		err := newHTTPError(tt.status, nil, cause)
+		if !errors.Is(err, cause) {
+			t.Errorf("status %d: error %v does not wrap the cause", tt.status, err)
+		}
+		if tt.kind != nil && !errors.Is(err, tt.kind) {
+			t.Errorf("status %d: error %v, want %v", tt.status, err, tt.kind)
+		}
+		if tt.kind == nil && domain.IsRetryableLLMError(err) {
+			t.Errorf("status %d: error %v should not be retried", tt.status, err)
+		}
+	}
+
+	header := http.Header{"Retry-After-Ms": []string{"1500"}}
+	if got := parseRetryAfter(header); got != 1500*time.Millisecond {
+		t.Errorf("parseRetryAfter() = %v, want 1.5s", got)
+	}
+}
@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// DefaultUsageLogPath returns <user config dir>/muserstory/usage.jsonl.
+func DefaultUsageLogPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "muserstory", "usage.jsonl"), nil
+}
+
+// LogsUsage reports whether the usage of provider belongs in the usage log. The offline
+// provider costs nothing, so its requests would only distort the totals.
+func LogsUsage(provider string) bool {
+	return provider != "offline"
+}
+
+// AppendUsageLog adds records to the usage log at path, one JSON object per line.
+func AppendUsageLog(path string, records []domain.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating usage log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening usage log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal usage record: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing usage log %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadUsageLog reads all records of the usage log at path. A missing log has no records.
+func ReadUsageLog(path string) ([]domain.UsageRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening usage log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var records []domain.UsageRecord
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record domain.UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("error parsing usage log %s line %d: %w", path, lineNumber, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading usage log %s: %w", path, err)
+	}
+	return records, nil
+}
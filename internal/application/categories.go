@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// ListCategories returns every category with its number of stories. When the file declares
+// a taxonomy, its empty categories are listed too.
+func (s *UserStoryService) ListCategories(ctx context.Context) (*CategoryList, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories: %w", err)
+	}
+	taxonomy, err := domain.ParseTaxonomy(markdownFile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &CategoryList{Categories: markdownFile.CategoryCounts(taxonomy), HasTaxonomy: taxonomy != nil}, nil
+}
+
+// RenameCategory moves all stories of category oldName to newName. With updateTaxonomy the
+// category is renamed in the taxonomy of the front matter as well.
+func (s *UserStoryService) RenameCategory(ctx context.Context, oldName string, newName string, updateTaxonomy bool) (*CategoryMove, error) {
+	return s.MergeCategories(ctx, []string{oldName}, newName, updateTaxonomy)
+}
+
+// MergeCategories moves all stories of the source categories into the category into, which
+// may be new or one of the sources. With updateTaxonomy the sources are replaced by into
+// in the taxonomy of the front matter as well.
+func (s *UserStoryService) MergeCategories(ctx context.Context, sources []string, into string, updateTaxonomy bool) (*CategoryMove, error) {
+	into, err := domain.ValidateCategoryName(into)
+	if err != nil {
+		return nil, err
+	}
+	for i, source := range sources {
+		if sources[i], err = domain.ValidateCategoryName(source); err != nil {
+			return nil, err
+		}
+	}
+
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories: %w", err)
+	}
+	moved := markdownFile.RecategorizeStories(sources, into)
+	taxonomyChanged := false
+	if updateTaxonomy {
+		if taxonomyChanged, err = markdownFile.ReplaceTaxonomyCategories(sources, into); err != nil {
+			return nil, err
+		}
+	}
+	if moved == 0 && !taxonomyChanged {
+		return nil, fmt.Errorf("no stories found in category '%s'", strings.Join(sources, "', '"))
+	}
+
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write stories to file: %w", err)
+	}
+	move := &CategoryMove{Into: into, Moved: moved, TaxonomyUpdated: taxonomyChanged, Declared: true}
+	if taxonomy, err := domain.ParseTaxonomy(markdownFile.Metadata); err == nil && taxonomy != nil && !taxonomyChanged {
+		_, move.Declared = taxonomy.Resolve(into)
+	}
+	return move, nil
+}
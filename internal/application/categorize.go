@@ -0,0 +1,335 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+const DefaultCategorizeConcurrency = 4
+
+// CategorizeOptions controls how many LLM requests run at once and how stories are grouped.
+type CategorizeOptions struct {
+	// Concurrency is the number of requests in flight; values below 1 mean one.
+	Concurrency int
+	// BatchSize sends this many stories per structured request. Values below 2 categorize
+	// each story with its own request.
+	BatchSize int
+}
+
+type SingleCategoryResponse struct {
+	Category string `json:"category" jsonschema_description:"Category of the user story"`
+}
+
+type StoryCategory struct {
+	ID       string `json:"id" jsonschema_description:"ID of the user story"`
+	Category string `json:"category" jsonschema_description:"Category of the user story"`
+}
+
+type BatchCategoryResponse struct {
+	Categories []StoryCategory `json:"categories" jsonschema_description:"The category of each user story, by ID"`
+}
+
+// CategorizeAllStories assigns a category to every story and writes them back sorted by
+// category. A cancelled run saves the categories assigned so far.
+func (s *UserStoryService) CategorizeAllStories(ctx context.Context, opts CategorizeOptions) (*CategorizationResult, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories for categorization: %w", err)
+	}
+
+	if len(markdownFile.Stories) == 0 {
+		return &CategorizationResult{}, nil
+	}
+
+	// A declared taxonomy keeps categories stable between runs; otherwise the LLM proposes them.
+	taxonomy, err := domain.ParseTaxonomy(markdownFile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	var possibleCategories []string
+	if taxonomy != nil {
+		possibleCategories = taxonomy.Names()
+	} else {
+		possibleCategories = s.GeneratePossibleCategories(ctx, markdownFile.Stories)
+	}
+
+	promptData := domain.PromptData{Categories: possibleCategories, Taxonomy: taxonomy}
+	storyPrompt, err := s.renderPrompt(domain.PromptCategorize, promptData)
+	if err != nil {
+		return nil, err
+	}
+	batchPrompt, err := s.renderPrompt(domain.PromptCategorizeBatch, promptData)
+	if err != nil {
+		return nil, err
+	}
+
+	categorizedStories := make([]domain.UserStory, len(markdownFile.Stories))
+	copy(categorizedStories, markdownFile.Stories)
+
+	var batches [][]int
+	batchSize := opts.BatchSize
+	if batchSize < 2 {
+		batchSize = 1
+	}
+	for start := 0; start < len(categorizedStories); start += batchSize {
+		end := start + batchSize
+		if end > len(categorizedStories) {
+			end = len(categorizedStories)
+		}
+		batch := make([]int, 0, end-start)
+		for i := start; i < end; i++ {
+			batch = append(batch, i)
+		}
+		batches = append(batches, batch)
+	}
+
+	// Each worker only writes the stories of its own batch, so results need no locking;
+	// the mutex guards the progress output and the first fatal error.
+	var progressMu sync.Mutex
+	done := 0
+	var fatalErr error
+	reportProgress := func(count int) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		done += count
+		s.notify("Categorized %d/%d stories", done, len(categorizedStories))
+	}
+	stop := func(err error) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if fatalErr == nil {
+			fatalErr = err
+		}
+	}
+	stopped := func() bool {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		return fatalErr != nil || ctx.Err() != nil
+	}
+
+	categorizeBatch := func(batch []int) {
+		if stopped() {
+			return
+		}
+		if len(batch) > 1 {
+			missing, err := s.categorizeBatch(ctx, categorizedStories, batch, batchPrompt, taxonomy)
+			if err != nil {
+				stop(err)
+				return
+			}
+			reportProgress(len(batch) - len(missing))
+			batch = missing
+		}
+		for _, i := range batch {
+			story := &categorizedStories[i]
+			category, err := s.categorizeStory(ctx, *story, storyPrompt, taxonomy)
+			if err != nil {
+				stop(err)
+				return
+			}
+			story.Category = category
+			reportProgress(1)
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(batches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				categorizeBatch(batch)
+			}
+		}()
+	}
+	for _, batch := range batches {
+		work <- batch
+	}
+	close(work)
+	wg.Wait()
+
+	// A cancelled run keeps the categories assigned so far; the other stories are unchanged.
+	interrupted := ctx.Err()
+	if fatalErr != nil && interrupted == nil {
+		return nil, fmt.Errorf("categorization stopped, no changes were written: %w", fatalErr)
+	}
+
+	sort.SliceStable(categorizedStories, func(i, j int) bool {
+		return categorizedStories[i].Category < categorizedStories[j].Category
+	})
+
+	markdownFile.Stories = categorizedStories
+
+	err = markdownFile.WriteToFile(s.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not write categorized stories to file: %w", err)
+	}
+
+	result := &CategorizationResult{Categories: possibleCategories, Stories: categorizedStories}
+	if interrupted != nil {
+		return result, fmt.Errorf("categorization interrupted after %d of %d stories, progress was saved: %w", done, len(categorizedStories), interrupted)
+	}
+	return result, nil
+}
+
+// isFatalLLMError reports whether err makes further requests pointless: the credentials are
+// rejected, the rate limit still applies after the LLM service's own retries, or the
+// caller's ctx has ended. A single request running past its model timeout is not fatal.
+func isFatalLLMError(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, domain.ErrLLMAuthFailed) || errors.Is(err, domain.ErrLLMRateLimited) || ctx.Err() != nil
+}
+
+// categorizeStory asks for the category of a single story. Failures other than fatal ones
+// assign "Uncategorized", or the taxonomy's fallback.
+func (s *UserStoryService) categorizeStory(ctx context.Context, story domain.UserStory, systemMessage string, taxonomy *domain.Taxonomy) (string, error) {
+	category, err := s.askCategory(ctx, story.Description, systemMessage, taxonomy)
+	if isFatalLLMError(ctx, err) {
+		return "", err
+	}
+	if err != nil {
+		fallback := domain.DefaultFallbackCategory
+		if taxonomy != nil {
+			fallback = taxonomy.Fallback
+		}
+		s.notify("Error categorizing story ID %s ('%s'): %v. Assigning '%s'.", story.ID, story.Description, err, fallback)
+		return fallback, nil
+	}
+	return category, nil
+}
+
+// askCategory asks for the category of a story description. Without a taxonomy the answer
+// is free text; with one it is constrained by a schema enum, and answers outside the
+// taxonomy are replaced by its fallback.
+func (s *UserStoryService) askCategory(ctx context.Context, description string, systemMessage string, taxonomy *domain.Taxonomy) (string, error) {
+	if taxonomy == nil {
+		response, err := s.llmService.AskSimple(ctx, domain.LLMSimpleInput{
+			SystemMessage: systemMessage,
+			UserMessage:   description,
+			ModelType:     domain.ModelTypeSimple,
+			Name:          "CategorizeUserStory",
+		})
+		if err != nil {
+			return "", err
+		}
+		category := strings.TrimSpace(response.Content)
+		if category == "" {
+			category = domain.DefaultFallbackCategory
+		}
+		return category, nil
+	}
+
+	response, err := s.llmService.AskAdvanced(ctx, domain.LLMAdvancedInput{
+		SystemMessage:     systemMessage,
+		UserMessage:       description,
+		ModelType:         domain.ModelTypeSimple,
+		SchemaName:        "CategorizeUserStory",
+		Schema:            taxonomy.ConstrainSchema(domain.GenerateSchema[SingleCategoryResponse]()),
+		SchemaDescription: "The category of the user story.",
+	})
+	if err != nil {
+		return "", err
+	}
+	var result SingleCategoryResponse
+	if err := json.Unmarshal([]byte(response.Content), &result); err != nil {
+		return "", fmt.Errorf("failed to unmarshal llm response for category: %w. Response was: %s", err, response.Content)
+	}
+	return s.resolveCategory(taxonomy, description, result.Category), nil
+}
+
+// resolveCategory maps an answer to the taxonomy, reporting answers it had to replace.
+func (s *UserStoryService) resolveCategory(taxonomy *domain.Taxonomy, description string, answer string) string {
+	category, ok := taxonomy.Resolve(answer)
+	if !ok {
+		s.notify("Category '%s' for story '%s' is not in the taxonomy. Assigning '%s'.", answer, description, category)
+	}
+	return category
+}
+
+// categoryPrompt renders the prompt for categorizing new stories of markdownFile, listing
+// its taxonomy when it declares one.
+func (s *UserStoryService) categoryPrompt(markdownFile *domain.MarkdownFile) (string, *domain.Taxonomy, error) {
+	taxonomy, err := domain.ParseTaxonomy(markdownFile.Metadata)
+	if err != nil {
+		return "", nil, err
+	}
+	var data domain.PromptData
+	if taxonomy != nil {
+		data = domain.PromptData{Categories: taxonomy.Names(), Taxonomy: taxonomy}
+	}
+	systemMessage, err := s.renderPrompt(domain.PromptCategorize, data)
+	if err != nil {
+		return "", nil, err
+	}
+	return systemMessage, taxonomy, nil
+}
+
+// categorizeBatch categorizes the stories at the given indexes with one structured request
+// and returns the indexes the response did not cover.
+func (s *UserStoryService) categorizeBatch(ctx context.Context, stories []domain.UserStory, batch []int, systemMessage string, taxonomy *domain.Taxonomy) ([]int, error) {
+	schema := domain.GenerateSchema[BatchCategoryResponse]()
+	if taxonomy != nil {
+		schema = taxonomy.ConstrainSchema(schema)
+	}
+	var storyList strings.Builder
+	for _, i := range batch {
+		storyList.WriteString(fmt.Sprintf("- ID %s: %s\n", stories[i].ID, stories[i].Description))
+	}
+
+	llmInput := domain.LLMAdvancedInput{
+		SystemMessage:     systemMessage,
+		UserMessage:       storyList.String(),
+		ModelType:         domain.ModelTypeSimple,
+		SchemaName:        "CategorizeUserStories",
+		Schema:            schema,
+		SchemaDescription: "The category of each user story, by ID.",
+	}
+	rawResponse, err := s.llmService.AskAdvanced(ctx, llmInput)
+	if isFatalLLMError(ctx, err) {
+		return nil, err
+	}
+	if err != nil {
+		s.notify("Error categorizing a batch of %d stories: %v. Categorizing them one by one.", len(batch), err)
+		return batch, nil
+	}
+	var response BatchCategoryResponse
+	if err := json.Unmarshal([]byte(rawResponse.Content), &response); err != nil {
+		s.notify("Error unmarshalling batch categorization response: %v. Categorizing them one by one.", err)
+		return batch, nil
+	}
+
+	categoryByID := make(map[string]string, len(response.Categories))
+	for _, result := range response.Categories {
+		if category := strings.TrimSpace(result.Category); category != "" {
+			categoryByID[strings.TrimSpace(result.ID)] = category
+		}
+	}
+
+	var missing []int
+	for _, i := range batch {
+		category, ok := categoryByID[stories[i].ID]
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		if taxonomy != nil {
+			category = s.resolveCategory(taxonomy, stories[i].Description, category)
+		}
+		stories[i].Category = category
+	}
+	return missing, nil
+}
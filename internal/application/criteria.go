@@ -0,0 +1,130 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// CriteriaOptions controls how acceptance criteria are drafted for a story.
+type CriteriaOptions struct {
+	// Count is the number of criteria to draft; zero lets the model decide.
+	Count int
+	// Replace discards the story's existing criteria instead of adding to them.
+	Replace bool
+	// SkipConfirm saves the drafted criteria without asking.
+	SkipConfirm bool
+}
+
+type AcceptanceCriteriaResponse struct {
+	Criteria []string `json:"criteria" jsonschema_description:"Acceptance criteria for the user story, each a sentence or a Given/When/Then scenario with one step per line"`
+}
+
+// DraftAcceptanceCriteria asks the LLM for acceptance criteria for the story with the given
+// UUID or UUID prefix, shows them and, once confirmed, adds them to the story.
+func (s *UserStoryService) DraftAcceptanceCriteria(ctx context.Context, id string, opts CriteriaOptions) (*CriteriaDraft, error) {
+	if opts.Count < 0 {
+		return nil, fmt.Errorf("number of criteria must not be negative")
+	}
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories: %w", err)
+	}
+	index, err := markdownFile.FindStory(id)
+	if err != nil {
+		return nil, err
+	}
+	story := markdownFile.Stories[index]
+	existing := story.AcceptanceCriteria
+	if opts.Replace {
+		existing = nil
+	}
+
+	var userMessage strings.Builder
+	if markdownFile.Summary != "" {
+		userMessage.WriteString(fmt.Sprintf("Project summary: %s\n\n", markdownFile.Summary))
+	}
+	userMessage.WriteString(fmt.Sprintf("User story: %s\nCategory: %s\n", story.Description, story.Category))
+	if len(existing) > 0 {
+		userMessage.WriteString("Existing acceptance criteria:\n")
+		for _, criterion := range existing {
+			userMessage.WriteString(fmt.Sprintf("- %s\n", strings.ReplaceAll(criterion, "\n", " ")))
+		}
+	}
+
+	systemMessage, err := s.renderPrompt(domain.PromptCriteria, domain.PromptData{Count: opts.Count})
+	if err != nil {
+		return nil, err
+	}
+	rawResponse, err := s.llmService.AskAdvanced(ctx, domain.LLMAdvancedInput{
+		SystemMessage:     systemMessage,
+		UserMessage:       userMessage.String(),
+		ModelType:         domain.ModelTypeReasoningSimple,
+		SchemaName:        "DraftAcceptanceCriteria",
+		Schema:            domain.GenerateSchema[AcceptanceCriteriaResponse](),
+		SchemaDescription: "Acceptance criteria drafted for a user story.",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("llm service failed to draft acceptance criteria: %w", err)
+	}
+	var response AcceptanceCriteriaResponse
+	if err := json.Unmarshal([]byte(rawResponse.Content), &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal llm response for acceptance criteria: %w. Response was: %s", err, rawResponse.Content)
+	}
+
+	var drafted []string
+	for _, criterion := range response.Criteria {
+		criterion = strings.TrimSpace(criterion)
+		if criterion == "" || containsCriterion(existing, criterion) || containsCriterion(drafted, criterion) {
+			continue
+		}
+		drafted = append(drafted, criterion)
+	}
+	draft := &CriteriaDraft{Story: story, Criteria: drafted}
+	if len(drafted) == 0 {
+		return draft, nil
+	}
+
+	s.notify("Drafted acceptance criteria for \"%s\":", story.Description)
+	s.printCriteria(drafted)
+	if !opts.SkipConfirm {
+		answer, err := s.prompt(ctx, "Save these criteria? (y/n): ")
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToLower(answer) != "y" {
+			return draft, nil
+		}
+	}
+
+	markdownFile.Stories[index].AcceptanceCriteria = append(append([]string(nil), existing...), drafted...)
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write acceptance criteria to file: %w", err)
+	}
+	draft.Story, draft.Saved = markdownFile.Stories[index], true
+	return draft, nil
+}
+
+func containsCriterion(criteria []string, criterion string) bool {
+	for _, existing := range criteria {
+		if strings.EqualFold(strings.Join(strings.Fields(existing), " "), strings.Join(strings.Fields(criterion), " ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// printCriteria shows each criterion as a bullet, with the further steps of a scenario
+// indented below its first line.
+func (s *UserStoryService) printCriteria(criteria []string) {
+	for _, criterion := range criteria {
+		steps := strings.Split(criterion, "\n")
+		s.notify("  - %s", strings.TrimSpace(steps[0]))
+		for _, step := range steps[1:] {
+			s.notify("    %s", strings.TrimSpace(step))
+		}
+	}
+}
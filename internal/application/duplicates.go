@@ -0,0 +1,311 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+const DefaultDuplicateThreshold = 0.6
+
+// maxSemanticCandidates bounds how many existing stories, the most similar first, are sent
+// to the LLM when checking a single new story for duplicates.
+const maxSemanticCandidates = 10
+
+// DuplicateCheckOptions controls how stories are compared with each other.
+type DuplicateCheckOptions struct {
+	// Threshold is the minimum local similarity (0-1) for two stories to count as duplicates.
+	Threshold float64
+	// Semantic additionally asks the LLM which stories describe the same functionality.
+	Semantic bool
+}
+
+type DuplicateMatch struct {
+	Story      domain.UserStory `json:"story"`
+	Similarity float64          `json:"similarity"`
+	// Semantic is set when the LLM judged the stories to be duplicates.
+	Semantic bool `json:"semantic"`
+}
+
+// DuplicateCluster is a group of stories that are likely duplicates of each other, in file order.
+type DuplicateCluster struct {
+	Stories []domain.UserStory `json:"stories"`
+}
+
+type DuplicateCheckResponse struct {
+	DuplicateIDs []string `json:"duplicate_ids" jsonschema_description:"IDs of the existing user stories that describe the same functionality as the new story"`
+}
+
+type DuplicateGroup struct {
+	IDs []string `json:"ids" jsonschema_description:"IDs of user stories that describe the same functionality"`
+}
+
+type DuplicateGroupsResponse struct {
+	Groups []DuplicateGroup `json:"groups" jsonschema_description:"Groups of duplicate user stories; stories without duplicates are left out"`
+}
+
+// FindDuplicates returns the existing stories that are likely duplicates of description,
+// most similar first.
+func (s *UserStoryService) FindDuplicates(ctx context.Context, description string, existing []domain.UserStory, opts DuplicateCheckOptions) ([]DuplicateMatch, error) {
+	var matches []DuplicateMatch
+	var candidates []DuplicateMatch
+	for _, story := range existing {
+		similarity := domain.DescriptionSimilarity(description, story.Description)
+		if similarity >= opts.Threshold {
+			matches = append(matches, DuplicateMatch{Story: story, Similarity: similarity})
+		} else {
+			// Stories without a word in common are candidates too: catching reworded
+			// duplicates is what the semantic check is for.
+			candidates = append(candidates, DuplicateMatch{Story: story, Similarity: similarity})
+		}
+	}
+
+	if opts.Semantic && len(candidates) > 0 {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Similarity > candidates[j].Similarity
+		})
+		if len(candidates) > maxSemanticCandidates {
+			candidates = candidates[:maxSemanticCandidates]
+		}
+
+		var candidateList strings.Builder
+		candidateList.WriteString("New user story:\n" + description + "\n\nExisting user stories:\n")
+		for _, candidate := range candidates {
+			candidateList.WriteString(fmt.Sprintf("- ID %s: %s\n", candidate.Story.ID, candidate.Story.Description))
+		}
+
+		systemMessage, err := s.renderPrompt(domain.PromptDuplicateCheck, domain.PromptData{})
+		if err != nil {
+			return nil, err
+		}
+		llmInput := domain.LLMAdvancedInput{
+			SystemMessage:     systemMessage,
+			UserMessage:       candidateList.String(),
+			ModelType:         domain.ModelTypeSimple,
+			SchemaName:        "FindDuplicateUserStories",
+			Schema:            domain.GenerateSchema[DuplicateCheckResponse](),
+			SchemaDescription: "IDs of existing user stories that duplicate the new story.",
+		}
+		rawResponse, err := s.llmService.AskAdvanced(ctx, llmInput)
+		if err != nil {
+			return nil, fmt.Errorf("llm service failed to check for duplicates: %w", err)
+		}
+		var response DuplicateCheckResponse
+		if err := json.Unmarshal([]byte(rawResponse.Content), &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal llm response for duplicate check: %w. Response was: %s", err, rawResponse.Content)
+		}
+		for _, id := range response.DuplicateIDs {
+			for _, candidate := range candidates {
+				if candidate.Story.ID == strings.TrimSpace(id) {
+					candidate.Semantic = true
+					matches = append(matches, candidate)
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Similarity > matches[j].Similarity
+	})
+	return matches, nil
+}
+
+// FindDuplicateClusters groups stories that are likely duplicates of each other. Clusters are
+// ordered by the position of their first story in the file.
+func (s *UserStoryService) FindDuplicateClusters(ctx context.Context, stories []domain.UserStory, opts DuplicateCheckOptions) ([]DuplicateCluster, error) {
+	parent := make([]int, len(stories))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		rootI, rootJ := find(i), find(j)
+		if rootI < rootJ {
+			parent[rootJ] = rootI
+		} else if rootJ < rootI {
+			parent[rootI] = rootJ
+		}
+	}
+
+	for i := range stories {
+		for j := i + 1; j < len(stories); j++ {
+			if domain.DescriptionSimilarity(stories[i].Description, stories[j].Description) >= opts.Threshold {
+				union(i, j)
+			}
+		}
+	}
+
+	if opts.Semantic && len(stories) > 1 {
+		indexByID := make(map[string]int, len(stories))
+		var storyList strings.Builder
+		for i, story := range stories {
+			indexByID[story.ID] = i
+			storyList.WriteString(fmt.Sprintf("- ID %s: %s\n", story.ID, story.Description))
+		}
+
+		systemMessage, err := s.renderPrompt(domain.PromptDuplicateGroups, domain.PromptData{})
+		if err != nil {
+			return nil, err
+		}
+		llmInput := domain.LLMAdvancedInput{
+			SystemMessage:     systemMessage,
+			UserMessage:       storyList.String(),
+			ModelType:         domain.ModelTypeAdvanced,
+			SchemaName:        "GroupDuplicateUserStories",
+			Schema:            domain.GenerateSchema[DuplicateGroupsResponse](),
+			SchemaDescription: "Groups of user stories that duplicate each other.",
+		}
+		rawResponse, err := s.llmService.AskAdvanced(ctx, llmInput)
+		if err != nil {
+			return nil, fmt.Errorf("llm service failed to group duplicates: %w", err)
+		}
+		var response DuplicateGroupsResponse
+		if err := json.Unmarshal([]byte(rawResponse.Content), &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal llm response for duplicate groups: %w. Response was: %s", err, rawResponse.Content)
+		}
+		for _, group := range response.Groups {
+			first := -1
+			for _, id := range group.IDs {
+				index, ok := indexByID[strings.TrimSpace(id)]
+				if !ok {
+					continue
+				}
+				if first == -1 {
+					first = index
+				} else {
+					union(first, index)
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]domain.UserStory)
+	var roots []int
+	for i, story := range stories {
+		root := find(i)
+		if _, exists := members[root]; !exists {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], story)
+	}
+
+	var clusters []DuplicateCluster
+	for _, root := range roots {
+		if len(members[root]) > 1 {
+			clusters = append(clusters, DuplicateCluster{Stories: members[root]})
+		}
+	}
+	return clusters, nil
+}
+
+// DedupeStories finds clusters of likely duplicate stories. With merge set, each cluster is
+// shown, the user picks which story to keep and the others are removed from the file.
+func (s *UserStoryService) DedupeStories(ctx context.Context, opts DuplicateCheckOptions, merge bool) (*DedupeResult, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories for duplicate detection: %w", err)
+	}
+
+	clusters, err := s.FindDuplicateClusters(ctx, markdownFile.Stories, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &DedupeResult{Clusters: clusters}
+	if len(clusters) == 0 || !merge {
+		return result, nil
+	}
+
+	s.notify("Found %d groups of likely duplicates.", len(clusters))
+	removed := make(map[string]bool)
+	// interrupted is set when the context is cancelled; merges made so far are still saved.
+	var interrupted error
+	for i, cluster := range clusters {
+		s.notify("\nGroup %d/%d:", i+1, len(clusters))
+		for j, story := range cluster.Stories {
+			s.notify("  %d. %s [Category: %s] [UUID: %s]", j+1, story.Description, story.Category, story.ID)
+		}
+
+		answer, err := s.prompt(ctx, fmt.Sprintf("Keep which story? (1-%d, Enter to skip): ", len(cluster.Stories)))
+		if err != nil {
+			interrupted = err
+			break
+		}
+		if answer == "" {
+			s.notify("Group skipped.")
+			continue
+		}
+		choice, err := strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(cluster.Stories) {
+			s.notify("Invalid choice, group skipped.")
+			continue
+		}
+
+		kept := cluster.Stories[choice-1]
+		merged := kept
+		for j, story := range cluster.Stories {
+			if j == choice-1 {
+				continue
+			}
+			if merged.Category == "Uncategorized" && story.Category != "" {
+				merged.Category = story.Category
+			}
+			if merged.Status == "" {
+				merged.Status = story.Status
+			}
+			removed[story.ID] = true
+		}
+		for j := range markdownFile.Stories {
+			if markdownFile.Stories[j].ID == kept.ID {
+				markdownFile.Stories[j] = merged
+			}
+		}
+		s.notify("Kept \"%s\", merged %d duplicates.", merged.Description, len(cluster.Stories)-1)
+	}
+
+	if len(removed) == 0 {
+		if interrupted != nil {
+			return nil, interrupted
+		}
+		return result, nil
+	}
+
+	remaining := make([]domain.UserStory, 0, len(markdownFile.Stories)-len(removed))
+	for _, story := range markdownFile.Stories {
+		if !removed[story.ID] {
+			remaining = append(remaining, story)
+		}
+	}
+	markdownFile.Stories = remaining
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write merged stories to file: %w", err)
+	}
+	result.Removed = len(removed)
+	if interrupted != nil {
+		return result, fmt.Errorf("dedupe interrupted, merges so far were saved: %w", interrupted)
+	}
+	return result, nil
+}
+
+// printDuplicateMatches shows the existing stories a new story resembles.
+func (s *UserStoryService) printDuplicateMatches(matches []DuplicateMatch) {
+	s.notify("Possible duplicates of existing stories:")
+	for _, match := range matches {
+		if match.Semantic {
+			s.notify("  - \"%s\" [UUID: %s] (same meaning according to the LLM)", match.Story.Description, match.Story.ID)
+			continue
+		}
+		s.notify("  - \"%s\" [UUID: %s] (%.0f%% similar)", match.Story.Description, match.Story.ID, match.Similarity*100)
+	}
+}
@@ -0,0 +1,186 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// PriorityScheme is how prioritize expresses priorities.
+type PriorityScheme string
+
+const (
+	// PrioritySchemeMoSCoW assigns Must, Should, Could or Won't.
+	PrioritySchemeMoSCoW PriorityScheme = "moscow"
+	// PrioritySchemeNumeric assigns every story its position in the ranking, from 1.
+	PrioritySchemeNumeric PriorityScheme = "numeric"
+)
+
+func ParsePriorityScheme(name string) (PriorityScheme, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "moscow":
+		return PrioritySchemeMoSCoW, nil
+	case "numeric", "number", "rank":
+		return PrioritySchemeNumeric, nil
+	}
+	return "", fmt.Errorf("unknown priority scheme '%s', expected moscow or numeric", name)
+}
+
+type PrioritizeOptions struct {
+	Scheme PriorityScheme
+	// SkipConfirm applies the proposed priorities without asking.
+	SkipConfirm bool
+}
+
+type RankedStory struct {
+	ID        string `json:"id" jsonschema_description:"ID of the user story"`
+	Priority  string `json:"priority" jsonschema_description:"Priority of the user story"`
+	Rationale string `json:"rationale" jsonschema_description:"One sentence on why the story has this place in the ranking"`
+}
+
+type PrioritizeResponse struct {
+	Ranking []RankedStory `json:"ranking" jsonschema_description:"The user stories ordered from most to least important"`
+}
+
+// PriorityProposal is the priority proposed for one story and why.
+type PriorityProposal struct {
+	Story     domain.UserStory `json:"story"`
+	Priority  domain.Priority  `json:"priority"`
+	Rationale string           `json:"rationale"`
+}
+
+// PrioritizeStories asks the LLM to rank the stories that are not done or declined, shows
+// the ranking with the rationale of each story and, once confirmed, sets the priorities.
+func (s *UserStoryService) PrioritizeStories(ctx context.Context, opts PrioritizeOptions) (*PrioritizationResult, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories for prioritization: %w", err)
+	}
+	var open []domain.UserStory
+	for _, story := range markdownFile.Stories {
+		if story.Status != domain.StatusDone && story.Status != domain.StatusDeclined {
+			open = append(open, story)
+		}
+	}
+	result := &PrioritizationResult{Open: len(open)}
+	if len(open) == 0 {
+		return result, nil
+	}
+
+	proposals, err := s.proposePriorities(ctx, markdownFile.Summary, open, opts.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	result.Proposals = proposals
+	if len(proposals) == 0 {
+		return result, nil
+	}
+
+	s.notify("Proposed ranking:")
+	for i, proposal := range proposals {
+		change := ""
+		if proposal.Story.Priority != "" && proposal.Story.Priority != proposal.Priority {
+			change = fmt.Sprintf(" (was %s)", proposal.Story.Priority)
+		}
+		s.notify("%3d. [%s] %s%s", i+1, proposal.Priority, proposal.Story.Description, change)
+		if proposal.Rationale != "" {
+			s.notify("     Why: %s", proposal.Rationale)
+		}
+	}
+	if unranked := len(open) - len(proposals); unranked > 0 {
+		s.notify("%d stories were not ranked and keep their priority.", unranked)
+	}
+	if !opts.SkipConfirm {
+		answer, err := s.prompt(ctx, "Apply these priorities? (y/n): ")
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToLower(answer) != "y" {
+			return result, nil
+		}
+	}
+
+	priorityByID := make(map[string]domain.Priority, len(proposals))
+	for _, proposal := range proposals {
+		priorityByID[proposal.Story.ID] = proposal.Priority
+	}
+	for i, story := range markdownFile.Stories {
+		if priority, ok := priorityByID[story.ID]; ok {
+			markdownFile.Stories[i].Priority = priority
+		}
+	}
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write priorities to file: %w", err)
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// proposePriorities asks the LLM for a ranking of stories. Unknown or repeated IDs and
+// invalid priorities in the answer are skipped.
+func (s *UserStoryService) proposePriorities(ctx context.Context, summary string, stories []domain.UserStory, scheme PriorityScheme) ([]PriorityProposal, error) {
+	data := domain.PromptData{}
+	schema := domain.GenerateSchema[PrioritizeResponse]()
+	if scheme != PrioritySchemeNumeric {
+		for _, priority := range domain.MoSCoWPriorities() {
+			data.Priorities = append(data.Priorities, string(priority))
+		}
+		schema = domain.ConstrainEnum(schema, "priority", data.Priorities)
+	}
+	systemMessage, err := s.renderPrompt(domain.PromptPrioritize, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var userMessage strings.Builder
+	if summary != "" {
+		userMessage.WriteString(fmt.Sprintf("Project summary: %s\n\n", summary))
+	}
+	for _, story := range stories {
+		userMessage.WriteString(fmt.Sprintf("- ID %s: %s %s\n", story.ID, story.Description, storyFieldTags(story)))
+	}
+
+	rawResponse, err := s.llmService.AskAdvanced(ctx, domain.LLMAdvancedInput{
+		SystemMessage:     systemMessage,
+		UserMessage:       userMessage.String(),
+		ModelType:         domain.ModelTypeReasoningSimple,
+		SchemaName:        "PrioritizeUserStories",
+		Schema:            schema,
+		SchemaDescription: "A ranking of the user stories with a priority and rationale for each.",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("llm service failed to prioritize stories: %w", err)
+	}
+	var response PrioritizeResponse
+	if err := json.Unmarshal([]byte(rawResponse.Content), &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal llm response for prioritization: %w. Response was: %s", err, rawResponse.Content)
+	}
+
+	storyByID := make(map[string]domain.UserStory, len(stories))
+	for _, story := range stories {
+		storyByID[story.ID] = story
+	}
+	var proposals []PriorityProposal
+	for _, ranked := range response.Ranking {
+		id := strings.TrimSpace(ranked.ID)
+		story, ok := storyByID[id]
+		if !ok {
+			continue
+		}
+		delete(storyByID, id)
+
+		priority := domain.Priority(fmt.Sprint(len(proposals) + 1))
+		if scheme != PrioritySchemeNumeric {
+			priority, err = domain.ParsePriority(ranked.Priority)
+			if err != nil || !priority.IsMoSCoW() {
+				s.notify("Skipping \"%s\": the LLM answered priority '%s'.", story.Description, ranked.Priority)
+				continue
+			}
+		}
+		proposals = append(proposals, PriorityProposal{Story: story, Priority: priority, Rationale: strings.TrimSpace(ranked.Rationale)})
+	}
+	return proposals, nil
+}
@@ -0,0 +1,42 @@
+package application
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+// SetPromptOverrides replaces built-in prompts, typically with those of a prompts directory.
+// Prompts in the front matter of the story file take precedence over these.
+func (s *UserStoryService) SetPromptOverrides(overrides map[domain.PromptName]domain.PromptTemplate) {
+	s.promptOverrides = overrides
+}
+
+// Prompts returns the prompts in effect for the story file that was read last: the
+// built-in ones, overridden by SetPromptOverrides and then by the file's front matter.
+func (s *UserStoryService) Prompts() (*domain.PromptSet, error) {
+	frontMatter, err := domain.FrontMatterPrompts(s.metadata)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPromptSet(domain.DefaultPromptTemplates(), s.promptOverrides, frontMatter)
+}
+
+// EffectivePrompts reads the story file, if there is one, and returns the prompts in effect for it.
+func (s *UserStoryService) EffectivePrompts() (*domain.PromptSet, error) {
+	if _, err := s.ReadUserStoriesFromFile(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return s.Prompts()
+}
+
+// renderPrompt renders the named system prompt with the front matter of the story file.
+func (s *UserStoryService) renderPrompt(name domain.PromptName, data domain.PromptData) (string, error) {
+	prompts, err := s.Prompts()
+	if err != nil {
+		return "", err
+	}
+	data.Metadata = s.metadata
+	return prompts.Render(name, data)
+}
@@ -0,0 +1,143 @@
+package application
+
+import "github.com/morgansundqvist/muserstory/internal/domain"
+
+// StoryList is the result of ListUserStories.
+type StoryList struct {
+	// Stories are the stories matching the query, in the order it asks for.
+	Stories []domain.UserStory `json:"stories"`
+	// Total is the number of stories in the file before filtering.
+	Total   int    `json:"total"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// AddedStory is the result of AddUserStory.
+type AddedStory struct {
+	// Added is false when the user declined to add a likely duplicate.
+	Added bool             `json:"added"`
+	Story domain.UserStory `json:"story"`
+	// Duplicates are the existing stories the new one resembles.
+	Duplicates []DuplicateMatch `json:"duplicates,omitempty"`
+}
+
+// CategorizationResult is the result of CategorizeAllStories.
+type CategorizationResult struct {
+	// Categories are the taxonomy or the categories proposed by the LLM.
+	Categories []string           `json:"categories"`
+	Stories    []domain.UserStory `json:"stories"`
+}
+
+// GeneratedStories is the result of GenerateNewStories.
+type GeneratedStories struct {
+	Stories []domain.UserStory `json:"stories"`
+	// DryRun is set when the stories were not written to the file.
+	DryRun bool `json:"dry_run"`
+}
+
+// Summary is the result of SummarizeStories.
+type Summary struct {
+	Text string `json:"summary"`
+}
+
+// StatusChange is the result of SetStoryStatus.
+type StatusChange struct {
+	Story domain.UserStory `json:"story"`
+	// Previous is the status the story had, empty when it had none.
+	Previous domain.Status `json:"previous_status"`
+}
+
+// StoryEdit is the result of EditUserStory.
+type StoryEdit struct {
+	Before domain.UserStory `json:"before"`
+	After  domain.UserStory `json:"after"`
+	// Applied is false when the user declined the change.
+	Applied bool `json:"applied"`
+}
+
+// StoryRemoval is the result of RemoveUserStory.
+type StoryRemoval struct {
+	Story domain.UserStory `json:"story"`
+	// Removed is false when the user declined the removal.
+	Removed bool `json:"removed"`
+}
+
+// ExportResult is the result of ExportStories.
+type ExportResult struct {
+	Stories int                 `json:"stories"`
+	Format  domain.ExportFormat `json:"format"`
+	// Path is the file the stories were written to. Without one, Content holds the export.
+	Path    string `json:"path,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// ImportResult is the result of ImportStories.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	// Total is the number of entries in the import file.
+	Total int `json:"total"`
+	// Skipped says why each left out entry was skipped.
+	Skipped []string `json:"skipped,omitempty"`
+	// Flagged are the descriptions of imported stories that duplicate existing ones.
+	Flagged []string `json:"flagged,omitempty"`
+}
+
+// DedupeResult is the result of DedupeStories.
+type DedupeResult struct {
+	Clusters []DuplicateCluster `json:"clusters"`
+	// Removed is the number of stories merged into the one kept of their group.
+	Removed int `json:"removed"`
+}
+
+// CriteriaDraft is the result of DraftAcceptanceCriteria.
+type CriteriaDraft struct {
+	Story domain.UserStory `json:"story"`
+	// Criteria are the new criteria, without the ones the story already had.
+	Criteria []string `json:"criteria"`
+	// Saved is false when the user declined the criteria or none were drafted.
+	Saved bool `json:"saved"`
+}
+
+// PrioritizationResult is the result of PrioritizeStories.
+type PrioritizationResult struct {
+	// Open is the number of stories that are not done or declined.
+	Open      int                `json:"open"`
+	Proposals []PriorityProposal `json:"proposals"`
+	// Applied is false when the user declined the priorities or none were proposed.
+	Applied bool `json:"applied"`
+}
+
+// ReviewResult is the result of ReviewProposedStories.
+type ReviewResult struct {
+	Accepted int `json:"accepted"`
+	Declined int `json:"declined"`
+	// Remaining is the number of stories that are still proposed.
+	Remaining int `json:"remaining"`
+}
+
+// CategoryList is the result of ListCategories.
+type CategoryList struct {
+	Categories []domain.CategoryCount `json:"categories"`
+	// HasTaxonomy is set when the file declares a taxonomy, which Declared refers to.
+	HasTaxonomy bool `json:"has_taxonomy"`
+}
+
+// CategoryMove is the result of MergeCategories and RenameCategory.
+type CategoryMove struct {
+	Into            string `json:"into"`
+	Moved           int    `json:"moved"`
+	TaxonomyUpdated bool   `json:"taxonomy_updated"`
+	// Declared is false when the file declares a taxonomy that does not contain Into.
+	Declared bool `json:"declared"`
+}
+
+// PushResult is the result of PushProject.
+type PushResult struct {
+	ProjectID   string `json:"project_id,omitempty"`
+	ProjectName string `json:"project_name"`
+	Stories     int    `json:"stories"`
+	URL         string `json:"url"`
+	// New is set when the file had no project ID yet.
+	New bool `json:"new"`
+	// DryRun is set when the project was not sent and the file not written.
+	DryRun bool `json:"dry_run"`
+}
@@ -0,0 +1,185 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+type ReviewOptions struct {
+	// Reviewer is recorded on the stories that are accepted or declined.
+	Reviewer string
+}
+
+// validateReviewer trims the name recorded in the Decided By tag and rejects names that
+// would break the story line.
+func validateReviewer(reviewer string) (string, error) {
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		return "", fmt.Errorf("reviewer must not be empty")
+	}
+	if strings.ContainsAny(reviewer, "[]\n") {
+		return "", fmt.Errorf("reviewer '%s' must not contain brackets or line breaks", reviewer)
+	}
+	return reviewer, nil
+}
+
+// ReviewProposedStories walks through the stories with the Proposed status in file order.
+// Each one can be accepted, declined, edited, recategorized or skipped; decisions are saved
+// with the reviewer and time, also when the review is quit or cancelled halfway. The
+// statuses are those of the file's workflow, whose transitions each decision must follow.
+func (s *UserStoryService) ReviewProposedStories(ctx context.Context, opts ReviewOptions) (*ReviewResult, error) {
+	reviewer, err := validateReviewer(opts.Reviewer)
+	if err != nil {
+		return nil, err
+	}
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories for review: %w", err)
+	}
+	workflow, err := domain.WorkflowFromMetadata(markdownFile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	proposed, ok := workflow.Resolve(string(domain.StatusProposed))
+	if !ok {
+		return nil, fmt.Errorf("the workflow has no '%s' state to review", domain.StatusProposed)
+	}
+
+	var queue []int
+	for i, story := range markdownFile.Stories {
+		if proposed.Matches(string(story.Status)) {
+			queue = append(queue, i)
+		}
+	}
+	if len(queue) == 0 {
+		return &ReviewResult{}, nil
+	}
+
+	categorizeMessage, taxonomy, err := s.categoryPrompt(markdownFile)
+	if err != nil {
+		return nil, err
+	}
+	var others []domain.UserStory
+	for _, story := range markdownFile.Stories {
+		if !proposed.Matches(string(story.Status)) {
+			others = append(others, story)
+		}
+	}
+
+	accepted, declined, changed := 0, 0, false
+	// interrupted is set when the context is cancelled; decisions made so far are still saved.
+	var interrupted error
+	quit := false
+	for n, index := range queue {
+		if quit || interrupted != nil {
+			break
+		}
+		story := &markdownFile.Stories[index]
+		s.notify("\nProposed story %d/%d: \"%s\" %s", n+1, len(queue), story.Description, storyFieldTags(*story))
+		if matches, err := s.FindDuplicates(ctx, story.Description, others, DuplicateCheckOptions{Threshold: DefaultDuplicateThreshold}); err == nil && len(matches) > 0 {
+			s.printDuplicateMatches(matches)
+		}
+
+	decide:
+		for {
+			answer, err := s.prompt(ctx, "(a)ccept, (d)ecline, (e)dit, (r)ecategorize, (s)kip or (q)uit: ")
+			if err != nil {
+				interrupted = err
+				break
+			}
+			switch strings.ToLower(answer) {
+			case "a", "accept":
+				if err := s.decideStory(workflow, story, domain.StatusAccepted, reviewer); err != nil {
+					s.notify("Could not accept the story: %v", err)
+					continue
+				}
+				others = append(others, *story)
+				accepted++
+				changed = true
+				break decide
+			case "d", "decline":
+				if err := s.decideStory(workflow, story, domain.StatusDeclined, reviewer); err != nil {
+					s.notify("Could not decline the story: %v", err)
+					continue
+				}
+				declined++
+				changed = true
+				break decide
+			case "e", "edit":
+				description, err := s.prompt(ctx, "New description (empty keeps it): ")
+				if err != nil {
+					interrupted = err
+					break decide
+				}
+				if description != "" {
+					if description, err = validateDescription(description); err != nil {
+						s.notify("Could not edit the story: %v", err)
+						continue
+					}
+					story.Description = description
+					changed = true
+				}
+				s.notify("Story: \"%s\" %s", story.Description, storyFieldTags(*story))
+			case "r", "recategorize":
+				category, err := s.prompt(ctx, "New category (empty asks the LLM): ")
+				if err != nil {
+					interrupted = err
+					break decide
+				}
+				if category == "" {
+					category, err = s.askCategory(ctx, story.Description, categorizeMessage, taxonomy)
+				} else {
+					category, err = domain.ValidateCategoryName(category)
+				}
+				if err != nil {
+					s.notify("Could not recategorize the story: %v", err)
+					continue
+				}
+				story.Category = category
+				changed = true
+				s.notify("Story: \"%s\" %s", story.Description, storyFieldTags(*story))
+			case "s", "skip", "":
+				break decide
+			case "q", "quit":
+				quit = true
+				break decide
+			default:
+				s.notify("Unknown choice '%s'.", answer)
+			}
+		}
+	}
+
+	if changed {
+		if err := markdownFile.WriteToFile(s.filePath); err != nil {
+			return nil, fmt.Errorf("could not write reviewed stories to file: %w", err)
+		}
+	}
+	result := &ReviewResult{Accepted: accepted, Declined: declined, Remaining: len(queue) - accepted - declined}
+	if interrupted != nil {
+		return result, fmt.Errorf("review interrupted after %d accepted and %d declined stories: %w", accepted, declined, interrupted)
+	}
+	return result, nil
+}
+
+// decideStory moves a proposed story to the workflow state named status and records who
+// decided and when. The workflow must have that state and allow the move to it.
+func (s *UserStoryService) decideStory(workflow domain.Workflow, story *domain.UserStory, status domain.Status, reviewer string) error {
+	target, ok := workflow.Resolve(string(status))
+	if !ok {
+		return fmt.Errorf("the workflow has no '%s' state", status)
+	}
+	from, _ := workflow.Resolve(string(story.Status))
+	if !workflow.CanTransition(from, target) {
+		return fmt.Errorf("the workflow does not allow moving from '%s' to '%s'", from, target)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	story.Status = target
+	story.DecidedBy = reviewer
+	story.DecidedAt = &now
+	s.notify("Story %s by %s.", strings.ToLower(string(target)), reviewer)
+	return nil
+}
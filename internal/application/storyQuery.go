@@ -0,0 +1,197 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+type SortField string
+
+const (
+	SortByFile        SortField = "file"
+	SortByCategory    SortField = "category"
+	SortByStatus      SortField = "status"
+	SortByDescription SortField = "description"
+	SortByID          SortField = "id"
+	SortByPriority    SortField = "priority"
+)
+
+var sortFields = []SortField{SortByFile, SortByCategory, SortByStatus, SortByDescription, SortByID, SortByPriority}
+
+// StoryQuery selects and orders user stories. Empty fields do not filter; within a field
+// any value may match (OR), and all non-empty fields must match (AND).
+type StoryQuery struct {
+	// Categories also match the categories nested below them.
+	Categories []string
+	Statuses   []string
+	// Text is matched case-insensitively against the description, as a substring
+	// or as a regular expression when TextIsRegex is set.
+	Text        string
+	TextIsRegex bool
+	// IDs match full UUIDs or UUID prefixes.
+	IDs        []string
+	SortBy     SortField
+	Descending bool
+}
+
+// IsEmpty reports whether the query filters nothing.
+func (q StoryQuery) IsEmpty() bool {
+	return len(q.Categories) == 0 && len(q.Statuses) == 0 && q.Text == "" && len(q.IDs) == 0
+}
+
+// ParseSortField validates a sort field name. An empty name means file order.
+func ParseSortField(name string) (SortField, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return SortByFile, nil
+	}
+	for _, field := range sortFields {
+		if string(field) == name {
+			return field, nil
+		}
+	}
+	names := make([]string, len(sortFields))
+	for i, field := range sortFields {
+		names[i] = string(field)
+	}
+	return "", fmt.Errorf("unknown sort field '%s', valid fields are: %s", name, strings.Join(names, ", "))
+}
+
+// Apply returns the stories matching the query in the requested order.
+// The input slice is not modified.
+func (q StoryQuery) Apply(stories []domain.UserStory) ([]domain.UserStory, error) {
+	matchText, err := q.textMatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.UserStory, 0, len(stories))
+	for _, story := range stories {
+		if !q.matchesCategory(story) || !q.matchesStatus(story) || !q.matchesID(story) {
+			continue
+		}
+		if matchText != nil && !matchText(story.Description) {
+			continue
+		}
+		result = append(result, story)
+	}
+
+	less := q.lessFunc(result)
+	if less != nil {
+		sort.SliceStable(result, less)
+	} else if q.Descending {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	return result, nil
+}
+
+func (q StoryQuery) textMatcher() (func(string) bool, error) {
+	if q.Text == "" {
+		return nil, nil
+	}
+	if q.TextIsRegex {
+		re, err := regexp.Compile("(?i)" + q.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid text pattern: %w", err)
+		}
+		return re.MatchString, nil
+	}
+	needle := strings.ToLower(q.Text)
+	return func(description string) bool {
+		return strings.Contains(strings.ToLower(description), needle)
+	}, nil
+}
+
+func (q StoryQuery) matchesCategory(story domain.UserStory) bool {
+	if len(q.Categories) == 0 {
+		return true
+	}
+	for _, category := range q.Categories {
+		if domain.CategoryWithin(story.Category, category) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q StoryQuery) matchesStatus(story domain.UserStory) bool {
+	if len(q.Statuses) == 0 {
+		return true
+	}
+	for _, status := range q.Statuses {
+		if strings.EqualFold(status, "none") && story.Status == "" {
+			return true
+		}
+		if story.Status != "" && story.Status.Matches(status) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q StoryQuery) matchesID(story domain.UserStory) bool {
+	if len(q.IDs) == 0 {
+		return true
+	}
+	for _, id := range q.IDs {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id != "" && strings.HasPrefix(strings.ToLower(story.ID), id) {
+			return true
+		}
+	}
+	return false
+}
+
+// lessFunc orders stories by SortBy in the direction of Descending, or returns nil to keep
+// the file order.
+func (q StoryQuery) lessFunc(stories []domain.UserStory) func(i, j int) bool {
+	if q.SortBy == SortByPriority {
+		return priorityLessFunc(stories, q.Descending)
+	}
+	less := q.ascendingLessFunc(stories)
+	if less == nil || !q.Descending {
+		return less
+	}
+	return func(i, j int) bool { return less(j, i) }
+}
+
+// priorityLessFunc orders stories from most to least important, or the reverse when
+// descending. Stories without a priority go last in both directions, so it cannot be
+// reversed like the other orders.
+func priorityLessFunc(stories []domain.UserStory, descending bool) func(i, j int) bool {
+	return func(i, j int) bool {
+		rankI, okI := stories[i].Priority.Rank()
+		rankJ, okJ := stories[j].Priority.Rank()
+		if okI != okJ {
+			return okI
+		}
+		if descending {
+			return rankI > rankJ
+		}
+		return rankI < rankJ
+	}
+}
+
+// ascendingLessFunc orders stories by the fields other than priority in ascending order.
+func (q StoryQuery) ascendingLessFunc(stories []domain.UserStory) func(i, j int) bool {
+	switch q.SortBy {
+	case SortByCategory:
+		return func(i, j int) bool { return stories[i].Category < stories[j].Category }
+	case SortByStatus:
+		return func(i, j int) bool { return stories[i].Status < stories[j].Status }
+	case SortByDescription:
+		return func(i, j int) bool {
+			return strings.ToLower(stories[i].Description) < strings.ToLower(stories[j].Description)
+		}
+	case SortByID:
+		return func(i, j int) bool { return stories[i].ID < stories[j].ID }
+	default:
+		return nil
+	}
+}
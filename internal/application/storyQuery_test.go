@@ -0,0 +1,62 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
+
+func TestStoryQueryApply(t *testing.T) {
+	stories := []domain.UserStory{
+		{ID: "aaa111", Description: "As a user, I want to log in", Category: "Auth", Status: domain.StatusDone},
+		{ID: "bbb222", Description: "As an admin, I want to ban users", Category: "Admin", Priority: domain.PriorityCould},
+		{ID: "ccc333", Description: "As a user, I want to reset my password", Category: "Auth/Password Reset", Status: domain.StatusInProgress, Priority: domain.PriorityMust},
+		{ID: "ddd444", Description: "As a user, I want dark mode", Category: "UI", Status: domain.StatusProposed},
+	}
+
+	tests := []struct {
+		name    string
+		query   StoryQuery
+		wantIDs []string
+		wantErr bool
+	}{
+		{name: "empty query keeps file order", query: StoryQuery{}, wantIDs: []string{"aaa111", "bbb222", "ccc333", "ddd444"}},
+		{name: "category is case-insensitive", query: StoryQuery{Categories: []string{"auth"}}, wantIDs: []string{"aaa111", "ccc333"}},
+		{name: "category matches nested categories", query: StoryQuery{Categories: []string{"auth / password reset"}}, wantIDs: []string{"ccc333"}},
+		{name: "status matches loosely", query: StoryQuery{Statuses: []string{"in-progress", "done"}}, wantIDs: []string{"aaa111", "ccc333"}},
+		{name: "status none", query: StoryQuery{Statuses: []string{"none"}}, wantIDs: []string{"bbb222"}},
+		{name: "text substring", query: StoryQuery{Text: "PASSWORD"}, wantIDs: []string{"ccc333"}},
+		{name: "text regex", query: StoryQuery{Text: "^as an? (admin|user), i want (to )?d", TextIsRegex: true}, wantIDs: []string{"ddd444"}},
+		{name: "invalid regex", query: StoryQuery{Text: "(", TextIsRegex: true}, wantErr: true},
+		{name: "id prefix", query: StoryQuery{IDs: []string{"bb", "DDD4"}}, wantIDs: []string{"bbb222", "ddd444"}},
+		{name: "combined filters", query: StoryQuery{Categories: []string{"Auth"}, Text: "log"}, wantIDs: []string{"aaa111"}},
+		{name: "sort by description descending", query: StoryQuery{SortBy: SortByDescription, Descending: true}, wantIDs: []string{"bbb222", "ccc333", "aaa111", "ddd444"}},
+		{name: "sort by priority puts unprioritized last", query: StoryQuery{SortBy: SortByPriority}, wantIDs: []string{"ccc333", "bbb222", "aaa111", "ddd444"}},
+		{name: "sort by priority descending keeps unprioritized last", query: StoryQuery{SortBy: SortByPriority, Descending: true}, wantIDs: []string{"bbb222", "ccc333", "aaa111", "ddd444"}},
+		{name: "descending file order", query: StoryQuery{Descending: true}, wantIDs: []string{"ddd444", "ccc333", "bbb222", "aaa111"}},
+		{name: "sort by category is stable", query: StoryQuery{SortBy: SortByCategory}, wantIDs: []string{"bbb222", "aaa111", "ccc333", "ddd444"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Apply(stories)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotIDs []string
+			for _, story := range got {
+				gotIDs = append(gotIDs, story.ID)
+			}
+			if len(gotIDs) != len(tt.wantIDs) {
+				t.Fatalf("Apply() ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+			for i := range gotIDs {
+				if gotIDs[i] != tt.wantIDs[i] {
+					t.Fatalf("Apply() ids = %v, want %v", gotIDs, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
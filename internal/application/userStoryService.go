@@ -1,24 +1,26 @@
 package application
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/morgansundqvist/muserstory/internal/domain"
 	"github.com/morgansundqvist/muserstory/internal/ports"
 )
 
-// GetProjectRemote fetches a project by ID from the remote API and prints its user stories.
-func (s *UserStoryService) GetProjectRemote(id string) error {
+// GetProjectRemote fetches a project with its user stories by ID from the remote API.
+func (s *UserStoryService) GetProjectRemote(ctx context.Context, id string) (*domain.Project, error) {
 	if id == "" {
-		return fmt.Errorf("project id must be provided with --id flag")
+		return nil, fmt.Errorf("project id must be provided with --id flag")
 	}
 	apiHost := os.Getenv("API_HOST")
 	if apiHost == "" {
@@ -26,49 +28,77 @@ func (s *UserStoryService) GetProjectRemote(id string) error {
 	}
 	url := strings.TrimRight(apiHost, "/") + "/api/projects/" + id
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to GET project: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GET project: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("failed to fetch project, status: %s", resp.Status)
+		return nil, fmt.Errorf("failed to fetch project, status: %s", resp.Status)
 	}
 
 	var project domain.Project
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&project); err != nil {
-		return fmt.Errorf("failed to decode project response: %w", err)
+		return nil, fmt.Errorf("failed to decode project response: %w", err)
 	}
 
-	fmt.Printf("Project: %s (UUID: %s)\n", project.Name, project.ID)
-	if len(project.UserStories) == 0 {
-		fmt.Println("No user stories found for this project.")
-		return nil
-	}
-	fmt.Println("User Stories:")
-	for _, story := range project.UserStories {
-		fmt.Printf("- %s [Category: %s]\n", story.Description, story.Category)
-	}
-	return nil
+	return &project, nil
 }
 
 type UserStoryService struct {
 	llmService ports.LLMService
 	filePath   string
 	fileReader ports.FileReader
+	// interaction reports progress and asks for confirmation; see SetInteraction.
+	interaction ports.Interaction
+	// promptOverrides replace built-in prompts; see SetPromptOverrides.
+	promptOverrides map[domain.PromptName]domain.PromptTemplate
+	// metadata is the front matter of the story file that was read last.
+	metadata map[string]interface{}
 }
 
+// NewUserStoryService creates a service that reports nothing and fails every question with
+// ports.ErrInputRequired until SetInteraction connects it to a user.
 func NewUserStoryService(
 	llmService ports.LLMService, filePath string, fileReader ports.FileReader) *UserStoryService {
 	return &UserStoryService{
-		llmService: llmService,
-		filePath:   filePath,
-		fileReader: fileReader,
+		llmService:  llmService,
+		filePath:    filePath,
+		fileReader:  fileReader,
+		interaction: unattended{},
 	}
 }
 
+// SetInteraction sets where progress is reported and questions are answered.
+func (s *UserStoryService) SetInteraction(interaction ports.Interaction) {
+	s.interaction = interaction
+}
+
+// unattended is the interaction of a service that nobody watches, as in the HTTP server.
+type unattended struct{}
+
+func (unattended) Notify(message string) {}
+
+func (unattended) Ask(ctx context.Context, question string) (string, error) {
+	return "", fmt.Errorf("%w: %s", ports.ErrInputRequired, strings.TrimRight(strings.TrimSpace(question), ":"))
+}
+
+// notify formats a message and reports it to the interaction.
+func (s *UserStoryService) notify(format string, args ...interface{}) {
+	s.interaction.Notify(fmt.Sprintf(format, args...))
+}
+
+// prompt asks question and returns the answer; see ports.Interaction.
+func (s *UserStoryService) prompt(ctx context.Context, question string) (string, error) {
+	return s.interaction.Ask(ctx, question)
+}
+
 func generateID() string {
 	uuidID := uuid.NewString()
 	return uuidID
@@ -83,13 +113,24 @@ func (s *UserStoryService) ReadUserStoriesFromFile() (*domain.MarkdownFile, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse markdown file content: %w", err)
 	}
+	s.metadata = markdownFile.Metadata
 	return markdownFile, nil
 }
 
-func (s *UserStoryService) AddUserStory(description string) error {
+type AddStoryOptions struct {
+	Duplicates DuplicateCheckOptions
+	// Force adds the story without checking for duplicates.
+	Force bool
+	// Propose adds the story with the Proposed status, queued for review.
+	Propose bool
+}
+
+// AddUserStory categorizes description and appends it to the file. Likely duplicates are
+// shown and the user is asked whether to add the story anyway, unless opts.Force is set.
+func (s *UserStoryService) AddUserStory(ctx context.Context, description string, opts AddStoryOptions) (*AddedStory, error) {
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read existing stories: %w", err)
+		return nil, fmt.Errorf("could not read existing stories: %w", err)
 	}
 
 	newStory := domain.UserStory{
@@ -97,21 +138,36 @@ func (s *UserStoryService) AddUserStory(description string) error {
 		Description: description,
 		Category:    "Uncategorized",
 	}
-
-	llmInput := domain.LLMSimpleInput{
-		SystemMessage: "Categorize the following user story. Only return the category name.",
-		UserMessage:   newStory.Description,
-		ModelType:     domain.ModelTypeSimple,
+	if opts.Propose {
+		newStory.Status = domain.StatusProposed
 	}
+	result := &AddedStory{Story: newStory}
 
-	category, err := s.llmService.AskSimple(llmInput)
+	if !opts.Force {
+		matches, err := s.FindDuplicates(ctx, description, markdownFile.Stories, opts.Duplicates)
+		if err != nil {
+			return nil, fmt.Errorf("could not check for duplicates: %w", err)
+		}
+		result.Duplicates = matches
+		if len(matches) > 0 {
+			s.printDuplicateMatches(matches)
+			answer, err := s.prompt(ctx, "Add the story anyway? (y/n): ")
+			if err != nil {
+				return nil, err
+			}
+			if strings.ToLower(answer) != "y" {
+				return result, nil
+			}
+		}
+	}
 
+	systemMessage, taxonomy, err := s.categoryPrompt(markdownFile)
 	if err != nil {
-		return fmt.Errorf("could not categorize new story: %w", err)
+		return nil, err
 	}
-	category = strings.TrimSpace(category)
-	if category == "" {
-		category = "Uncategorized"
+	category, err := s.askCategory(ctx, newStory.Description, systemMessage, taxonomy)
+	if err != nil {
+		return nil, fmt.Errorf("could not categorize new story: %w", err)
 	}
 
 	newStory.Category = category
@@ -120,80 +176,179 @@ func (s *UserStoryService) AddUserStory(description string) error {
 
 	err = markdownFile.WriteToFile(s.filePath)
 	if err != nil {
-		return fmt.Errorf("could not write new story to file: %w", err)
+		return nil, fmt.Errorf("could not write new story to file: %w", err)
 	}
-	fmt.Printf("User story added: \"%s\" [Category: %s]\n", newStory.Description, newStory.Category)
-	return nil
+	result.Added, result.Story = true, newStory
+	return result, nil
 }
 
-func (s *UserStoryService) CategorizeAllStories() error {
+// SetStoryStatus moves the story with the given UUID (or UUID prefix) to a new workflow state.
+// The workflow is read from the file metadata; force skips the transition check.
+func (s *UserStoryService) SetStoryStatus(ctx context.Context, id string, state string, force bool) (*StatusChange, error) {
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read stories for categorization: %w", err)
+		return nil, fmt.Errorf("could not read stories: %w", err)
 	}
 
-	if len(markdownFile.Stories) == 0 {
-		return nil
+	workflow, err := domain.WorkflowFromMetadata(markdownFile.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("could not read workflow: %w", err)
+	}
+
+	newStatus, ok := workflow.Resolve(state)
+	if !ok {
+		states := make([]string, len(workflow.States))
+		for i, st := range workflow.States {
+			states[i] = string(st)
+		}
+		return nil, fmt.Errorf("unknown status '%s', valid states are: %s", state, strings.Join(states, ", "))
 	}
 
-	possibleCategories := s.GeneratePossibleCategories(markdownFile.Stories)
+	index, err := markdownFile.FindStory(id)
+	if err != nil {
+		return nil, err
+	}
 
-	possibleCategoriesString := strings.Join(possibleCategories, ", ")
+	story := &markdownFile.Stories[index]
+	if !force && !workflow.CanTransition(story.Status, newStatus) {
+		return nil, fmt.Errorf("cannot move story from '%s' to '%s' (use --force to override)", story.Status, newStatus)
+	}
+	previousStatus := story.Status
+	story.Status = newStatus
 
-	categorizedStories := make([]domain.UserStory, len(markdownFile.Stories))
-	for i, story := range markdownFile.Stories {
-		llmInput := domain.LLMSimpleInput{
-			SystemMessage: "Categorize the following user story. Only return the category name. Possible categories are: " + possibleCategoriesString,
-			UserMessage:   story.Description,
-			ModelType:     domain.ModelTypeSimple,
+	err = markdownFile.WriteToFile(s.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not write updated status to file: %w", err)
+	}
+	return &StatusChange{Story: *story, Previous: previousStatus}, nil
+}
+
+// StoryChanges holds the fields to update on a story; nil fields are left unchanged.
+type StoryChanges struct {
+	Description *string
+	Category    *string
+	// Priority and Estimate clear the field when they point to the zero value.
+	Priority *domain.Priority
+	Estimate *float64
+}
+
+// validateDescription trims a story description and rejects one that would break the
+// story line it is written on.
+func validateDescription(description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return "", fmt.Errorf("description must not be empty")
+	}
+	if strings.ContainsAny(description, "[]\n") {
+		return "", fmt.Errorf("description '%s' must not contain brackets or line breaks", description)
+	}
+	return description, nil
+}
+
+// EditUserStory updates the story with the given UUID or UUID prefix. Unless skipConfirm
+// is set, the change is shown and the user is asked to confirm it.
+func (s *UserStoryService) EditUserStory(ctx context.Context, id string, changes StoryChanges, skipConfirm bool) (*StoryEdit, error) {
+	if changes.Description == nil && changes.Category == nil && changes.Priority == nil && changes.Estimate == nil {
+		return nil, fmt.Errorf("nothing to change, provide --description, --category, --priority or --estimate")
+	}
+
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories: %w", err)
+	}
+	index, err := markdownFile.FindStory(id)
+	if err != nil {
+		return nil, err
+	}
+
+	original := markdownFile.Stories[index]
+	updated := original
+	if changes.Description != nil {
+		if updated.Description, err = validateDescription(*changes.Description); err != nil {
+			return nil, err
 		}
-		category, err := s.llmService.AskSimple(llmInput)
+	}
+	if changes.Category != nil {
+		updated.Category = "Uncategorized"
+		if strings.TrimSpace(*changes.Category) != "" {
+			if updated.Category, err = domain.ValidateCategoryName(*changes.Category); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if changes.Priority != nil {
+		updated.Priority = *changes.Priority
+	}
+	if changes.Estimate != nil {
+		updated.Estimate = *changes.Estimate
+	}
+
+	edit := &StoryEdit{Before: original, After: updated}
+	s.notify("Story %s:", original.ID)
+	s.notify("  before: \"%s\" %s", original.Description, storyFieldTags(original))
+	s.notify("  after:  \"%s\" %s", updated.Description, storyFieldTags(updated))
+	if !skipConfirm {
+		answer, err := s.prompt(ctx, "Apply this change? (y/n): ")
 		if err != nil {
-			fmt.Printf("Error categorizing story ID %s ('%s'): %v. Assigning 'Uncategorized'.\n", story.ID, story.Description, err)
-			categorizedStories[i] = story
-			categorizedStories[i].Category = "Uncategorized"
-			continue
+			return nil, err
 		}
-		category = strings.TrimSpace(category)
-		if category == "" {
-			category = "Uncategorized"
+		if strings.ToLower(answer) != "y" {
+			return edit, nil
 		}
-		categorizedStories[i] = story
-		categorizedStories[i].Category = category
 	}
 
-	sort.Slice(categorizedStories, func(i, j int) bool {
-		return categorizedStories[i].Category < categorizedStories[j].Category
-	})
-
-	markdownFile.Stories = categorizedStories
+	markdownFile.Stories[index] = updated
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write updated story to file: %w", err)
+	}
+	edit.Applied = true
+	return edit, nil
+}
 
-	err = markdownFile.WriteToFile(s.filePath)
+// RemoveUserStory deletes the story with the given UUID or UUID prefix, asking for
+// confirmation unless skipConfirm is set.
+func (s *UserStoryService) RemoveUserStory(ctx context.Context, id string, skipConfirm bool) (*StoryRemoval, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not write categorized stories to file: %w", err)
+		return nil, fmt.Errorf("could not read stories: %w", err)
+	}
+	index, err := markdownFile.FindStory(id)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println("User stories have been processed for categorization.")
-	if len(categorizedStories) > 0 {
-		fmt.Println("Current stories and their categories:")
-		for _, story := range categorizedStories {
-			fmt.Printf("  - \"%s\" [Category: %s]\n", story.Description, story.Category)
+	story := markdownFile.Stories[index]
+	removal := &StoryRemoval{Story: story}
+	s.notify("Story %s: \"%s\" [Category: %s]", story.ID, story.Description, story.Category)
+	if !skipConfirm {
+		answer, err := s.prompt(ctx, "Remove this story? (y/n): ")
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		fmt.Println("No stories were written back to the file after categorization attempt.")
+		if strings.ToLower(answer) != "y" {
+			return removal, nil
+		}
+	}
+
+	markdownFile.Stories = append(markdownFile.Stories[:index], markdownFile.Stories[index+1:]...)
+	if err := markdownFile.WriteToFile(s.filePath); err != nil {
+		return nil, fmt.Errorf("could not write stories to file: %w", err)
 	}
-	return nil
+	removal.Removed = true
+	return removal, nil
 }
 
-func (s *UserStoryService) SummarizeStories() error {
+// SummarizeStories asks the LLM for a summary of all stories and writes it to the file.
+// A file without stories is left unchanged and gives an empty summary.
+func (s *UserStoryService) SummarizeStories(ctx context.Context) (*Summary, error) {
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read stories for summarization: %w", err)
+		return nil, fmt.Errorf("could not read stories for summarization: %w", err)
 	}
 
 	if len(markdownFile.Stories) == 0 {
-		fmt.Println("No stories to summarize.")
-		return nil
+		s.notify("No stories to summarize.")
+		return &Summary{}, nil
 	}
 
 	var storyDescriptions strings.Builder
@@ -204,87 +359,236 @@ func (s *UserStoryService) SummarizeStories() error {
 		}
 	}
 
+	systemMessage, err := s.renderPrompt(domain.PromptSummarize, domain.PromptData{})
+	if err != nil {
+		return nil, err
+	}
 	llmInput := domain.LLMSimpleInput{
-		SystemMessage: "Please create a summary of what the project is based on the user stories which are input. Write about what is is based on the user stories but also what it could become. Do not include any preamble like 'Here is the summary:'.",
+		SystemMessage: systemMessage,
 		UserMessage:   storyDescriptions.String(),
 		ModelType:     domain.ModelTypeSimple,
+		Name:          "SummarizeUserStories",
 	}
 
-	generatedSummary, err := s.llmService.AskSimple(llmInput)
+	summaryResponse, err := s.llmService.AskSimple(ctx, llmInput)
 	if err != nil {
-		return fmt.Errorf("could not generate summary from LLM: %w", err)
+		return nil, fmt.Errorf("could not generate summary from LLM: %w", err)
 	}
 
-	generatedSummary = strings.TrimSpace(generatedSummary)
+	generatedSummary := strings.TrimSpace(summaryResponse.Content)
 
 	if generatedSummary == "" {
-		fmt.Println("LLM generated an empty summary. The file will be updated with no summary or an empty summary section.")
-	} else {
-		fmt.Println("# Summary")
-		fmt.Println(generatedSummary)
-		fmt.Println("\nSummary has been generated.")
+		s.notify("LLM generated an empty summary. The file will be updated with no summary or an empty summary section.")
 	}
 
 	markdownFile.Summary = generatedSummary
 	err = markdownFile.WriteToFile(s.filePath)
 	if err != nil {
-		return fmt.Errorf("could not write new summary and stories to file: %w", err)
+		return nil, fmt.Errorf("could not write new summary and stories to file: %w", err)
 	}
+	return &Summary{Text: generatedSummary}, nil
+}
 
-	fmt.Println("File has been updated with the new summary and existing stories.")
-	return nil
+// ListUserStories returns the stories matching query together with the number of stories
+// in the file.
+func (s *UserStoryService) ListUserStories(ctx context.Context, query StoryQuery) (*StoryList, error) {
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read stories for listing: %w", err)
+	}
+
+	stories, err := query.Apply(markdownFile.Stories)
+	if err != nil {
+		return nil, fmt.Errorf("could not filter stories: %w", err)
+	}
+	return &StoryList{Stories: stories, Total: len(markdownFile.Stories), Summary: markdownFile.Summary}, nil
 }
 
-func (s *UserStoryService) ListUserStories() error {
+// storyFieldTags shows the category, priority and estimate of a story as tags.
+func storyFieldTags(story domain.UserStory) string {
+	tags := fmt.Sprintf("[Category: %s]", story.Category)
+	if story.Priority != "" {
+		tags += fmt.Sprintf(" [Priority: %s]", story.Priority)
+	}
+	if story.Estimate > 0 {
+		tags += fmt.Sprintf(" [Estimate: %s]", domain.FormatEstimate(story.Estimate))
+	}
+	return tags
+}
+
+// ExportStories writes the stories matching query, together with the file metadata and summary,
+// to outPath in the given format. An empty outPath writes to stdout.
+func (s *UserStoryService) ExportStories(ctx context.Context, format domain.ExportFormat, outPath string, query StoryQuery) (*ExportResult, error) {
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read stories for listing: %w", err)
+		return nil, fmt.Errorf("could not read stories for export: %w", err)
 	}
 
-	if len(markdownFile.Stories) == 0 {
-		if markdownFile.Summary == "" {
-			fmt.Println("No user stories found in the file.")
-		} else {
-			fmt.Println("No user stories found in the file (summary is present).")
+	stories, err := query.Apply(markdownFile.Stories)
+	if err != nil {
+		return nil, fmt.Errorf("could not filter stories: %w", err)
+	}
+	exported := *markdownFile
+	exported.Stories = stories
+
+	result := &ExportResult{Stories: len(stories), Format: format, Path: outPath}
+	if outPath == "" {
+		var content strings.Builder
+		if err := exported.Export(&content, format); err != nil {
+			return nil, fmt.Errorf("could not export stories: %w", err)
 		}
-		return nil
+		result.Content = content.String()
+		return result, nil
+	}
+	if err := exported.ExportToFile(outPath, format); err != nil {
+		return nil, fmt.Errorf("could not export stories: %w", err)
 	}
+	return result, nil
+}
 
-	// Group stories by category
-	categoryMap := make(map[string][]domain.UserStory)
-	var categories []string
+type DuplicatePolicy string
+
+const (
+	// DuplicatesSkip leaves out imported stories whose description already exists.
+	DuplicatesSkip DuplicatePolicy = "skip"
+	// DuplicatesFlag imports them anyway and reports them for review.
+	DuplicatesFlag DuplicatePolicy = "flag"
+)
+
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(strings.ToLower(strings.TrimSpace(name))) {
+	case "", DuplicatesSkip:
+		return DuplicatesSkip, nil
+	case DuplicatesFlag:
+		return DuplicatesFlag, nil
+	}
+	return "", fmt.Errorf("unknown duplicate policy '%s', expected skip or flag", name)
+}
+
+// ImportStories reads stories from importPath and appends them to the markdown file,
+// creating it if needed. Existing UUIDs are preserved; stories whose UUID is already
+// in the file are always skipped, stories with a known description follow policy.
+func (s *UserStoryService) ImportStories(ctx context.Context, importPath string, format domain.ImportFormat, mapping domain.ImportMapping, policy DuplicatePolicy) (*ImportResult, error) {
+	content, err := s.fileReader.ReadFileContent(importPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	imported, err := domain.ParseImportedStories(strings.NewReader(content), format, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse import file: %w", err)
+	}
+
+	markdownFile, err := s.ReadUserStoriesFromFile()
+	if errors.Is(err, fs.ErrNotExist) {
+		markdownFile = &domain.MarkdownFile{}
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read existing stories: %w", err)
+	}
+
+	workflow, err := domain.WorkflowFromMetadata(markdownFile.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("could not read workflow: %w", err)
+	}
+
+	knownIDs := make(map[string]bool)
+	knownDescriptions := make(map[string]bool)
 	for _, story := range markdownFile.Stories {
-		category := story.Category
-		if _, exists := categoryMap[category]; !exists {
-			categories = append(categories, category)
-		}
-		categoryMap[category] = append(categoryMap[category], story)
+		knownIDs[strings.ToLower(story.ID)] = true
+		knownDescriptions[domain.NormalizeDescription(story.Description)] = true
 	}
 
-	// Sort categories alphabetically for consistent output
-	sort.Strings(categories)
+	addedCount := 0
+	var skipped, flagged []string
+	for i, story := range imported {
+		description, err := validateDescription(story.Description)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("entry %d: %v", i+1, err))
+			continue
+		}
+		story.Description = description
+		if story.Category != "" {
+			category, err := domain.ValidateCategoryName(story.Category)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("\"%s\": %v", story.Description, err))
+				continue
+			}
+			story.Category = category
+		}
+		if story.ID != "" && knownIDs[strings.ToLower(story.ID)] {
+			skipped = append(skipped, fmt.Sprintf("\"%s\": UUID %s already exists", story.Description, story.ID))
+			continue
+		}
+		normalized := domain.NormalizeDescription(story.Description)
+		if knownDescriptions[normalized] {
+			if policy == DuplicatesSkip {
+				skipped = append(skipped, fmt.Sprintf("\"%s\": duplicate description", story.Description))
+				continue
+			}
+			flagged = append(flagged, story.Description)
+		}
 
-	fmt.Println("User Stories:")
-	for i, category := range categories {
-		fmt.Printf("Category: %s\n", category)
-		for _, story := range categoryMap[category] {
-			fmt.Printf("- %s [UUID: %s]\n", story.Description, story.ID)
+		if story.ID == "" {
+			story.ID = generateID()
 		}
-		if i < len(categories)-1 {
-			fmt.Println()
+		if story.Category == "" {
+			story.Category = "Uncategorized"
 		}
+		if story.Status != "" {
+			resolved, ok := workflow.Resolve(string(story.Status))
+			if !ok {
+				s.notify("Unknown status '%s' for \"%s\", leaving it empty.", story.Status, story.Description)
+			}
+			story.Status = resolved
+		}
+
+		knownIDs[strings.ToLower(story.ID)] = true
+		knownDescriptions[normalized] = true
+		markdownFile.Stories = append(markdownFile.Stories, story)
+		addedCount++
 	}
-	return nil
+
+	if addedCount > 0 {
+		if err := markdownFile.WriteToFile(s.filePath); err != nil {
+			return nil, fmt.Errorf("could not write imported stories to file: %w", err)
+		}
+	}
+
+	return &ImportResult{Imported: addedCount, Total: len(imported), Skipped: skipped, Flagged: flagged}, nil
 }
 
 type GeneratedStoriesResponse struct {
 	NewUserStories []string `json:"new_user_stories" jsonschema_description:"A list of new user story descriptions."`
 }
 
-func (s *UserStoryService) GenerateNewStories(numStoriesToGenerate int) error {
+// GenerateOptions controls how many stories are generated and how they are added.
+type GenerateOptions struct {
+	Count      int
+	Duplicates DuplicateCheckOptions
+	// AcceptAll adds the stories as Accepted by Reviewer instead of queueing them for review.
+	AcceptAll bool
+	Reviewer  string
+	// DryRun prints the stories that would be added without writing the file.
+	DryRun bool
+}
+
+// GenerateNewStories asks the LLM for new stories and adds them with the Proposed status,
+// to be accepted or declined with ReviewProposedStories, or as Accepted with AcceptAll.
+func (s *UserStoryService) GenerateNewStories(ctx context.Context, opts GenerateOptions) (*GeneratedStories, error) {
+	if opts.Count <= 0 {
+		return nil, fmt.Errorf("number of stories must be positive")
+	}
+	var reviewer string
+	if opts.AcceptAll {
+		var err error
+		if reviewer, err = validateReviewer(opts.Reviewer); err != nil {
+			return nil, fmt.Errorf("cannot accept all stories: %w", err)
+		}
+	}
+	numStoriesToGenerate := opts.Count
+
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read existing stories: %w", err)
+		return nil, fmt.Errorf("could not read existing stories: %w", err)
 	}
 
 	var existingStoryDescriptions strings.Builder
@@ -297,10 +601,18 @@ func (s *UserStoryService) GenerateNewStories(numStoriesToGenerate int) error {
 		existingStoryDescriptions.WriteString("There are no existing user stories. Please generate initial stories for a new project.")
 	}
 
-	schemaDef := domain.GenerateSchema[GeneratedStoriesResponse]()
+	schemaDef := domain.ConstrainItems(domain.GenerateSchema[GeneratedStoriesResponse](), "new_user_stories", numStoriesToGenerate)
 
+	systemMessage, err := s.renderPrompt(domain.PromptGenerate, domain.PromptData{Count: numStoriesToGenerate})
+	if err != nil {
+		return nil, err
+	}
+	categorizeMessage, taxonomy, err := s.categoryPrompt(markdownFile)
+	if err != nil {
+		return nil, err
+	}
 	llmInput := domain.LLMAdvancedInput{
-		SystemMessage:     fmt.Sprintf("Based on the provided context of existing user stories (if any), generate exactly %d new, distinct, and relevant user stories. Each story should be a single descriptive sentence, typically following a format like 'As a [user type], I want [action] so that [benefit]'.", numStoriesToGenerate),
+		SystemMessage:     systemMessage,
 		UserMessage:       existingStoryDescriptions.String(),
 		ModelType:         domain.ModelTypeReasoningSimple,
 		SchemaName:        "GenerateNewUserStories",
@@ -308,94 +620,112 @@ func (s *UserStoryService) GenerateNewStories(numStoriesToGenerate int) error {
 		SchemaDescription: "A list of newly generated user story descriptions.",
 	}
 
-	rawResponse, err := s.llmService.AskAdvanced(llmInput)
+	rawResponse, err := s.llmService.AskAdvanced(ctx, llmInput)
 	if err != nil {
-		return fmt.Errorf("llm service failed to generate stories: %w", err)
+		return nil, fmt.Errorf("llm service failed to generate stories: %w", err)
 	}
 
 	var generatedStoriesResponse GeneratedStoriesResponse
-	if err := json.Unmarshal([]byte(rawResponse), &generatedStoriesResponse); err != nil {
-		return fmt.Errorf("failed to unmarshal llm response for generated stories: %w. Response was: %s", err, rawResponse)
+	if err := json.Unmarshal([]byte(rawResponse.Content), &generatedStoriesResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal llm response for generated stories: %w. Response was: %s", err, rawResponse.Content)
 	}
 
+	result := &GeneratedStories{DryRun: opts.DryRun}
 	if len(generatedStoriesResponse.NewUserStories) == 0 {
-		fmt.Println("LLM did not generate any new stories.")
-		return nil
+		s.notify("LLM did not generate any new stories.")
+		return result, nil
 	}
 
-	fmt.Printf("LLM generated %d potential new story descriptions. Reviewing each one...\n", len(generatedStoriesResponse.NewUserStories))
+	s.notify("LLM generated %d potential new story descriptions. Categorizing each one...", len(generatedStoriesResponse.NewUserStories))
 
 	allStories := markdownFile.Stories
-	newlyAddedStoriesCount := 0
-	reader := bufio.NewReader(os.Stdin)
+	// interrupted is set when the context is cancelled; stories added so far are still saved.
+	var interrupted error
 
 	for i, storyDesc := range generatedStoriesResponse.NewUserStories {
-		trimmedStoryDesc := strings.TrimSpace(storyDesc)
-		if trimmedStoryDesc == "" {
-			fmt.Println("Skipping empty story description generated by LLM.")
+		trimmedStoryDesc, err := validateDescription(storyDesc)
+		if err != nil {
+			s.notify("Skipping story generated by LLM: %v.", err)
 			continue
 		}
 
-		fmt.Printf("\nGenerated story %d/%d: \"%s\"\n", i+1, len(generatedStoriesResponse.NewUserStories), trimmedStoryDesc)
-		fmt.Print("Keep this story? (y/n): ")
-		userInput, _ := reader.ReadString('\n')
-		userInput = strings.ToLower(strings.TrimSpace(userInput))
-
-		if userInput != "y" {
-			fmt.Println("Story discarded.")
-			continue
+		s.notify("\nGenerated story %d/%d: \"%s\"", i+1, len(generatedStoriesResponse.NewUserStories), trimmedStoryDesc)
+		matches, err := s.FindDuplicates(ctx, trimmedStoryDesc, allStories, opts.Duplicates)
+		if err != nil {
+			s.notify("Could not check for duplicates: %v", err)
+		} else if len(matches) > 0 {
+			s.printDuplicateMatches(matches)
 		}
 
-		fmt.Println("Story accepted. Categorizing...")
 		newStory := domain.UserStory{
 			ID:          generateID(),
 			Description: trimmedStoryDesc,
 			Category:    "Uncategorized",
+			Status:      domain.StatusProposed,
 		}
 
-		categorizationInput := domain.LLMSimpleInput{
-			SystemMessage: "Categorize the following user story. Only return the category name.",
-			UserMessage:   newStory.Description,
-			ModelType:     domain.ModelTypeSimple,
+		category, catErr := s.askCategory(ctx, newStory.Description, categorizeMessage, taxonomy)
+		if err := ctx.Err(); err != nil {
+			interrupted = err
+			break
 		}
-		category, catErr := s.llmService.AskSimple(categorizationInput)
 		if catErr != nil {
-			fmt.Printf("Could not categorize new story \"%s\": %v. Assigning 'Uncategorized'.\n", newStory.Description, catErr)
-		} else {
-			trimmedCategory := strings.TrimSpace(category)
-			if trimmedCategory == "" {
-				newStory.Category = "Uncategorized"
-			} else {
-				newStory.Category = trimmedCategory
+			if taxonomy != nil {
+				newStory.Category = taxonomy.Fallback
 			}
+			s.notify("Could not categorize new story \"%s\": %v. Assigning '%s'.", newStory.Description, catErr, newStory.Category)
+		} else {
+			newStory.Category = category
 		}
 
+		verb := "Proposed"
+		if opts.AcceptAll {
+			now := time.Now().UTC().Truncate(time.Second)
+			newStory.Status = domain.StatusAccepted
+			newStory.DecidedBy = reviewer
+			newStory.DecidedAt = &now
+			verb = "Accepted"
+		}
+		if opts.DryRun {
+			verb = "Would add as " + strings.ToLower(verb)
+		}
 		allStories = append(allStories, newStory)
-		fmt.Printf("Kept and categorized: \"%s\" [Category: %s]\n", newStory.Description, newStory.Category)
-		newlyAddedStoriesCount++
+		result.Stories = append(result.Stories, newStory)
+		s.notify("%s: \"%s\" [Category: %s]", verb, newStory.Description, newStory.Category)
 	}
 
-	if newlyAddedStoriesCount == 0 {
-		fmt.Println("No valid new stories were generated or processed.")
-		return nil
+	if len(result.Stories) == 0 {
+		if interrupted != nil {
+			return nil, interrupted
+		}
+		s.notify("No valid new stories were generated or processed.")
+		return result, nil
+	}
+
+	if opts.DryRun {
+		if interrupted != nil {
+			return nil, fmt.Errorf("generation interrupted, nothing was written: %w", interrupted)
+		}
+		return result, nil
 	}
 
 	markdownFile.Stories = allStories
 
 	err = markdownFile.WriteToFile(s.filePath)
 	if err != nil {
-		return fmt.Errorf("could not write new stories to file: %w", err)
+		return nil, fmt.Errorf("could not write new stories to file: %w", err)
 	}
-
-	fmt.Printf("%d new user stories have been generated, categorized, and added to %s.\n", newlyAddedStoriesCount, s.filePath)
-	return nil
+	if interrupted != nil {
+		return result, fmt.Errorf("generation interrupted, %d new stories were saved to %s: %w", len(result.Stories), s.filePath, interrupted)
+	}
+	return result, nil
 }
 
 type CategoryResponse struct {
 	Categories []string `json:"categories" jsonschema_description:"List of possible categories for the user stories"`
 }
 
-func (s *UserStoryService) GeneratePossibleCategories(stories []domain.UserStory) []string {
+func (s *UserStoryService) GeneratePossibleCategories(ctx context.Context, stories []domain.UserStory) []string {
 	var categories []string
 
 	var storyDescriptions strings.Builder
@@ -406,8 +736,13 @@ func (s *UserStoryService) GeneratePossibleCategories(stories []domain.UserStory
 
 	responseInterface := domain.GenerateSchema[CategoryResponse]()
 
+	systemMessage, err := s.renderPrompt(domain.PromptPossibleCategories, domain.PromptData{})
+	if err != nil {
+		s.notify("Error generating categories: %v", err)
+		return nil
+	}
 	llmInput := domain.LLMAdvancedInput{
-		SystemMessage:     "Generate a list of possible categories based on the following user stories. Only return the category names.",
+		SystemMessage:     systemMessage,
 		UserMessage:       storyDescriptions.String(),
 		ModelType:         domain.ModelTypeSimple,
 		SchemaName:        "GeneratePossibleCategories",
@@ -415,17 +750,17 @@ func (s *UserStoryService) GeneratePossibleCategories(stories []domain.UserStory
 		SchemaDescription: "List of possible categories for the user stories",
 	}
 
-	categoriesResponse, err := s.llmService.AskAdvanced(llmInput)
+	categoriesResponse, err := s.llmService.AskAdvanced(ctx, llmInput)
 	if err != nil {
-		fmt.Printf("Error generating categories: %v\n", err)
+		s.notify("Error generating categories: %v", err)
 		return nil
 	}
 
 	var categoriesResponseStruct CategoryResponse
 
-	err = json.Unmarshal([]byte(categoriesResponse), &categoriesResponseStruct)
+	err = json.Unmarshal([]byte(categoriesResponse.Content), &categoriesResponseStruct)
 	if err != nil {
-		fmt.Printf("Error unmarshalling categories response: %v\n", err)
+		s.notify("Error unmarshalling categories response: %v", err)
 		return nil
 	}
 
@@ -434,10 +769,21 @@ func (s *UserStoryService) GeneratePossibleCategories(stories []domain.UserStory
 	return categories
 }
 
-func (s *UserStoryService) PushProject() error {
+// PushOptions controls how PushProject names the project and whether it is sent.
+type PushOptions struct {
+	// ProjectName names the project instead of the file metadata or a prompt.
+	ProjectName string
+	// DryRun prints the project that would be pushed without sending it or writing the file.
+	DryRun bool
+}
+
+// PushProject sends the stories as a project to the remote API and records the project ID
+// and name in the file metadata. The name is asked for when the file has none and
+// opts.ProjectName is empty.
+func (s *UserStoryService) PushProject(ctx context.Context, opts PushOptions) (*PushResult, error) {
 	markdownFile, err := s.ReadUserStoriesFromFile()
 	if err != nil {
-		return fmt.Errorf("could not read markdown file: %w", err)
+		return nil, fmt.Errorf("could not read markdown file: %w", err)
 	}
 
 	// Ensure metadata map exists
@@ -447,16 +793,20 @@ func (s *UserStoryService) PushProject() error {
 
 	projectID, _ := markdownFile.Metadata["project_id"].(string)
 	projectName, _ := markdownFile.Metadata["project_name"].(string)
+	if name := strings.TrimSpace(opts.ProjectName); name != "" {
+		projectName = name
+	}
 
 	// Prompt for project name and generate ID if missing
-	if projectID == "" {
+	isNew := projectID == ""
+	if isNew {
 		if projectName == "" {
-			fmt.Print("Enter project name: ")
-			reader := bufio.NewReader(os.Stdin)
-			nameInput, _ := reader.ReadString('\n')
-			projectName = strings.TrimSpace(nameInput)
+			projectName, err = s.prompt(ctx, "Enter project name: ")
+			if err != nil {
+				return nil, err
+			}
 			if projectName == "" {
-				return fmt.Errorf("project name cannot be empty")
+				return nil, fmt.Errorf("project name cannot be empty")
 			}
 		}
 		projectID = generateID()
@@ -478,72 +828,75 @@ func (s *UserStoryService) PushProject() error {
 	apiPath := "/api/projects"
 	url := strings.TrimRight(apiHost, "/") + apiPath
 
+	result := &PushResult{ProjectID: projectID, ProjectName: projectName, Stories: len(project.UserStories), URL: url, New: isNew, DryRun: opts.DryRun}
+	if opts.DryRun {
+		// A new project only keeps its generated ID once it has been pushed.
+		if isNew {
+			result.ProjectID = ""
+		}
+		return result, nil
+	}
+
 	// Marshal project to JSON
 	body, err := json.Marshal(project)
 	if err != nil {
-		return fmt.Errorf("failed to marshal project: %w", err)
+		return nil, fmt.Errorf("failed to marshal project: %w", err)
 	}
 
 	// HTTP POST
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to POST project: %w", err)
+		return nil, fmt.Errorf("failed to POST project: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("failed to push project to remote, status: %s", resp.Status)
+		return nil, fmt.Errorf("failed to push project to remote, status: %s", resp.Status)
 	}
 
 	// Write project_id and project_name to metadata and save
 	markdownFile.Metadata["project_id"] = projectID
 	markdownFile.Metadata["project_name"] = projectName
 	if err := markdownFile.WriteToFile(s.filePath); err != nil {
-		return fmt.Errorf("could not update markdown file with project metadata: %w", err)
+		return nil, fmt.Errorf("could not update markdown file with project metadata: %w", err)
 	}
 
-	fmt.Printf("Project pushed and metadata updated in %s\n", s.filePath)
-	return nil
+	return result, nil
 }
 
-// ListProjectsRemote fetches all projects from the remote API and prints their name and UUID.
-func (s *UserStoryService) ListProjectsRemote() error {
+// ListProjectsRemote fetches all projects from the remote API.
+func (s *UserStoryService) ListProjectsRemote(ctx context.Context) ([]domain.Project, error) {
 	apiHost := os.Getenv("API_HOST")
 	if apiHost == "" {
 		apiHost = "http://localhost:3000"
 	}
 	url := strings.TrimRight(apiHost, "/") + "/api/projects"
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to GET projects: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GET projects: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("failed to fetch projects, status: %s", resp.Status)
+		return nil, fmt.Errorf("failed to fetch projects, status: %s", resp.Status)
 	}
 
 	var projects []domain.Project
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&projects); err != nil {
-		return fmt.Errorf("failed to decode projects response: %w", err)
+		return nil, fmt.Errorf("failed to decode projects response: %w", err)
 	}
 
-	if len(projects) == 0 {
-		fmt.Println("No remote projects found.")
-		return nil
-	}
-
-	fmt.Println("Remote Projects:")
-	for _, project := range projects {
-		fmt.Printf("- %s (UUID: %s)\n", project.Name, project.ID)
-	}
-	return nil
+	return projects, nil
 }
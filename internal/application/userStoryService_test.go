@@ -0,0 +1,1076 @@
+package application_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morgansundqvist/muserstory/internal/adapters"
+	"github.com/morgansundqvist/muserstory/internal/application"
+	"github.com/morgansundqvist/muserstory/internal/domain"
+	"github.com/morgansundqvist/muserstory/internal/ports"
+)
+
+const testStoriesFile = `- As a user, I want to log in [Category: Auth] [UUID: aaa11111-0000-0000-0000-000000000001]
+- As a user, I want to fix the broken export [Category: Bug] [Status: Proposed] [UUID: bbb22222-0000-0000-0000-000000000002]
+- As an admin, I want to ban users [Category: Admin] [UUID: ccc33333-0000-0000-0000-000000000003]
+`
+
+// newTestService writes content to a temporary story file and returns a service using the
+// offline LLM, with prompts answered from answers.
+func newTestService(t *testing.T, content string, answers string) (*application.UserStoryService, *adapters.OfflineLLMService, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write story file: %v", err)
+		}
+	}
+	llm, err := adapters.NewOfflineLLMService(domain.LLMProviderConfig{})
+	if err != nil {
+		t.Fatalf("NewOfflineLLMService() error = %v", err)
+	}
+	svc := application.NewUserStoryService(llm, path, adapters.NewLocalFileReader())
+	svc.SetInteraction(adapters.NewConsoleInteraction(strings.NewReader(answers), io.Discard))
+	return svc, llm, path
+}
+
+func readStories(t *testing.T, svc *application.UserStoryService) *domain.MarkdownFile {
+	t.Helper()
+	markdownFile, err := svc.ReadUserStoriesFromFile()
+	if err != nil {
+		t.Fatalf("ReadUserStoriesFromFile() error = %v", err)
+	}
+	return markdownFile
+}
+
+func storyDescriptions(stories []domain.UserStory) []string {
+	descriptions := make([]string, len(stories))
+	for i, story := range stories {
+		descriptions[i] = story.Description
+	}
+	return descriptions
+}
+
+func TestReadUserStoriesFromFile(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	markdownFile := readStories(t, svc)
+	if len(markdownFile.Stories) != 3 {
+		t.Fatalf("got %d stories, want 3", len(markdownFile.Stories))
+	}
+	if markdownFile.Stories[1].Status != domain.StatusProposed {
+		t.Errorf("Status = %q, want %q", markdownFile.Stories[1].Status, domain.StatusProposed)
+	}
+
+	missing, _, _ := newTestService(t, "", "")
+	if _, err := missing.ReadUserStoriesFromFile(); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestAddUserStory(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	if _, err := svc.AddUserStory(t.Context(), "As a user, I want to export reports as PDF", application.AddStoryOptions{Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}}); err != nil {
+		t.Fatalf("AddUserStory() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 4 {
+		t.Fatalf("got %d stories, want 4", len(stories))
+	}
+	added := stories[3]
+	if added.Category != "Feature" || added.ID == "" {
+		t.Errorf("added story = %+v, want category Feature and an ID", added)
+	}
+}
+
+func TestAddUserStoryDuplicatePrompt(t *testing.T) {
+	opts := application.AddStoryOptions{Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}}
+
+	svc, _, _ := newTestService(t, testStoriesFile, "n\n")
+	result, err := svc.AddUserStory(t.Context(), "As an admin I want to ban users", opts)
+	if err != nil {
+		t.Fatalf("AddUserStory() error = %v", err)
+	}
+	if result.Added || len(result.Duplicates) != 1 {
+		t.Errorf("declined duplicate: result = %+v", result)
+	}
+	if got := len(readStories(t, svc).Stories); got != 3 {
+		t.Errorf("declined duplicate: got %d stories, want 3", got)
+	}
+
+	svc, _, _ = newTestService(t, testStoriesFile, "y\n")
+	if _, err := svc.AddUserStory(t.Context(), "As an admin I want to ban users", opts); err != nil {
+		t.Fatalf("AddUserStory() error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 4 {
+		t.Errorf("accepted duplicate: got %d stories, want 4", got)
+	}
+
+	opts.Force = true
+	svc, _, _ = newTestService(t, testStoriesFile, "")
+	if _, err := svc.AddUserStory(t.Context(), "As an admin I want to ban users", opts); err != nil {
+		t.Fatalf("AddUserStory() error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 4 {
+		t.Errorf("forced duplicate: got %d stories, want 4", got)
+	}
+}
+
+func TestSetStoryStatus(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	change, err := svc.SetStoryStatus(t.Context(), "bbb2", "accepted", false)
+	if err != nil {
+		t.Fatalf("SetStoryStatus() error = %v", err)
+	}
+	if change.Previous != domain.StatusProposed || change.Story.Status != domain.StatusAccepted {
+		t.Errorf("SetStoryStatus() = %+v", change)
+	}
+	if got := readStories(t, svc).Stories[1].Status; got != domain.StatusAccepted {
+		t.Errorf("Status = %q, want %q", got, domain.StatusAccepted)
+	}
+
+	if _, err := svc.SetStoryStatus(t.Context(), "bbb2", "done", false); err == nil {
+		t.Error("expected an error for a transition the workflow does not allow")
+	}
+	if _, err := svc.SetStoryStatus(t.Context(), "bbb2", "done", true); err != nil {
+		t.Errorf("forced SetStoryStatus() error = %v", err)
+	}
+	if _, err := svc.SetStoryStatus(t.Context(), "bbb2", "shipped", true); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+	if _, err := svc.SetStoryStatus(t.Context(), "zzz", "done", true); err == nil {
+		t.Error("expected an error for an unknown story")
+	}
+}
+
+func TestEditUserStory(t *testing.T) {
+	description := "As a user, I want to log in with SSO"
+	category := "Authentication"
+
+	svc, _, _ := newTestService(t, testStoriesFile, "n\n")
+	edit, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Description: &description}, false)
+	if err != nil {
+		t.Fatalf("EditUserStory() error = %v", err)
+	}
+	if edit.Applied || edit.After.Description != description {
+		t.Errorf("declined EditUserStory() = %+v", edit)
+	}
+	if got := readStories(t, svc).Stories[0].Description; got != "As a user, I want to log in" {
+		t.Errorf("declined edit changed description to %q", got)
+	}
+
+	if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Description: &description, Category: &category}, true); err != nil {
+		t.Fatalf("EditUserStory() error = %v", err)
+	}
+	story := readStories(t, svc).Stories[0]
+	if story.Description != description || story.Category != category {
+		t.Errorf("edited story = %+v", story)
+	}
+
+	if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{}, true); err == nil {
+		t.Error("expected an error when nothing changes")
+	}
+	empty := " "
+	if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Description: &empty}, true); err == nil {
+		t.Error("expected an error for an empty description")
+	}
+	for _, bad := range []string{"Log in [Status: Done]", "Log in\n- Another story"} {
+		if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Description: &bad}, true); err == nil {
+			t.Errorf("expected an error for description %q", bad)
+		}
+		if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Category: &bad}, true); err == nil {
+			t.Errorf("expected an error for category %q", bad)
+		}
+	}
+	nested := " Auth / SSO "
+	if _, err := svc.EditUserStory(t.Context(), "aaa1", application.StoryChanges{Category: &nested}, true); err != nil {
+		t.Fatalf("EditUserStory() error = %v", err)
+	}
+	if got := readStories(t, svc).Stories[0].Category; got != "Auth/SSO" {
+		t.Errorf("Category = %q, want the normalized path Auth/SSO", got)
+	}
+}
+
+func TestRemoveUserStory(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "n\ny\n")
+	if _, err := svc.RemoveUserStory(t.Context(), "ccc3", false); err != nil {
+		t.Fatalf("RemoveUserStory() error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 3 {
+		t.Fatalf("declined removal: got %d stories, want 3", got)
+	}
+	if _, err := svc.RemoveUserStory(t.Context(), "ccc3", false); err != nil {
+		t.Fatalf("RemoveUserStory() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 2 || stories[1].ID != "bbb22222-0000-0000-0000-000000000002" {
+		t.Errorf("remaining stories = %v", storyDescriptions(stories))
+	}
+}
+
+func TestDraftAcceptanceCriteria(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "n\ny\n")
+	if _, err := svc.DraftAcceptanceCriteria(t.Context(), "aaa1", application.CriteriaOptions{}); err != nil {
+		t.Fatalf("DraftAcceptanceCriteria() error = %v", err)
+	}
+	if got := readStories(t, svc).Stories[0].AcceptanceCriteria; len(got) != 0 {
+		t.Fatalf("declined criteria were saved: %q", got)
+	}
+
+	if _, err := svc.DraftAcceptanceCriteria(t.Context(), "aaa1", application.CriteriaOptions{}); err != nil {
+		t.Fatalf("DraftAcceptanceCriteria() error = %v", err)
+	}
+	criteria := readStories(t, svc).Stories[0].AcceptanceCriteria
+	if len(criteria) != 2 || !strings.HasPrefix(criteria[0], "Given ") || !strings.Contains(criteria[0], "\nThen ") {
+		t.Fatalf("AcceptanceCriteria = %q, want a scenario and a sentence", criteria)
+	}
+
+	// Criteria the story already has are not drafted again.
+	if _, err := svc.DraftAcceptanceCriteria(t.Context(), "aaa1", application.CriteriaOptions{SkipConfirm: true}); err != nil {
+		t.Fatalf("DraftAcceptanceCriteria() error = %v", err)
+	}
+	if got := readStories(t, svc).Stories[0].AcceptanceCriteria; len(got) != 2 {
+		t.Errorf("AcceptanceCriteria = %q, want the existing two", got)
+	}
+}
+
+func TestPrioritizeStories(t *testing.T) {
+	content := testStoriesFile + "- As a user, I want dark mode [Category: UI] [Status: Done] [UUID: ddd44444-0000-0000-0000-000000000004]\n"
+	svc, _, _ := newTestService(t, content, "y\n")
+	result, err := svc.PrioritizeStories(t.Context(), application.PrioritizeOptions{Scheme: application.PrioritySchemeMoSCoW})
+	if err != nil {
+		t.Fatalf("PrioritizeStories() error = %v", err)
+	}
+	if !result.Applied || result.Open != 3 || len(result.Proposals) != 3 {
+		t.Errorf("PrioritizeStories() = %+v", result)
+	}
+	var priorities []domain.Priority
+	for _, story := range readStories(t, svc).Stories {
+		priorities = append(priorities, story.Priority)
+	}
+	want := []domain.Priority{domain.PriorityMust, domain.PriorityShould, domain.PriorityCould, ""}
+	if !reflect.DeepEqual(priorities, want) {
+		t.Errorf("priorities = %q, want %q", priorities, want)
+	}
+
+	if _, err := svc.PrioritizeStories(t.Context(), application.PrioritizeOptions{Scheme: application.PrioritySchemeNumeric, SkipConfirm: true}); err != nil {
+		t.Fatalf("PrioritizeStories() error = %v", err)
+	}
+	if got := readStories(t, svc).Stories[2].Priority; got != "3" {
+		t.Errorf("numeric priority of the third story = %q, want 3", got)
+	}
+}
+
+func TestCategorizeAllStories(t *testing.T) {
+	options := map[string]application.CategorizeOptions{
+		"sequential":         {},
+		"concurrent":         {Concurrency: 3},
+		"batched":            {Concurrency: 2, BatchSize: 2},
+		"single large batch": {BatchSize: 10},
+	}
+	for name, opts := range options {
+		t.Run(name, func(t *testing.T) {
+			svc, _, _ := newTestService(t, testStoriesFile, "")
+			if _, err := svc.CategorizeAllStories(t.Context(), opts); err != nil {
+				t.Fatalf("CategorizeAllStories() error = %v", err)
+			}
+			stories := readStories(t, svc).Stories
+			var got []string
+			for _, story := range stories {
+				got = append(got, story.ID[:3]+"="+story.Category)
+			}
+			// Ties keep file order, so the result does not depend on which request finishes first.
+			want := "bbb=Bug,aaa=Feature,ccc=Feature"
+			if strings.Join(got, ",") != want {
+				t.Errorf("categorized stories = %v, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCategorizeAllStoriesBatchFallback(t *testing.T) {
+	svc, llm, _ := newTestService(t, testStoriesFile, "")
+	// The batch answer only covers one story; the others are categorized one by one.
+	llm.AddFixture(domain.PromptHash(domain.ModelTypeSimple,
+		"Categorize each of the following user stories. Return the category name for every story ID. Possible categories are: Bug, Feature, Chore, Technical Debt",
+		"- ID aaa11111-0000-0000-0000-000000000001: As a user, I want to log in\n"+
+			"- ID bbb22222-0000-0000-0000-000000000002: As a user, I want to fix the broken export\n"+
+			"- ID ccc33333-0000-0000-0000-000000000003: As an admin, I want to ban users\n",
+		"CategorizeUserStories"), `{"categories":[{"id":"aaa11111-0000-0000-0000-000000000001","category":"Chore"}]}`)
+	if _, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{BatchSize: 3}); err != nil {
+		t.Fatalf("CategorizeAllStories() error = %v", err)
+	}
+	var got []string
+	for _, story := range readStories(t, svc).Stories {
+		got = append(got, story.ID[:3]+"="+story.Category)
+	}
+	if want := "bbb=Bug,aaa=Chore,ccc=Feature"; strings.Join(got, ",") != want {
+		t.Errorf("categorized stories = %v, want %s", got, want)
+	}
+}
+
+type failingLLMService struct {
+	err error
+}
+
+func (f failingLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	return domain.LLMResponse{}, f.err
+}
+
+func (f failingLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	return domain.LLMResponse{}, f.err
+}
+
+func TestCategorizeAllStoriesWithTaxonomy(t *testing.T) {
+	content := "---\ncategories:\n  - Export\n  - name: Admin\n    description: Moderation and user management\ncategory_fallback: Other\n---\n" + testStoriesFile
+	for _, batchSize := range []int{0, 3} {
+		svc, _, _ := newTestService(t, content, "")
+		if _, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{BatchSize: batchSize}); err != nil {
+			t.Fatalf("CategorizeAllStories() error = %v", err)
+		}
+		categories := make(map[string]string)
+		for _, story := range readStories(t, svc).Stories {
+			categories[story.Description] = story.Category
+		}
+		// The offline LLM answers "Feature" for the login story, which is not in the taxonomy.
+		want := map[string]string{
+			"As a user, I want to log in":                "Other",
+			"As a user, I want to fix the broken export": "Export",
+			"As an admin, I want to ban users":           "Admin",
+		}
+		for description, category := range want {
+			if categories[description] != category {
+				t.Errorf("batch size %d: %q got category %q, want %q", batchSize, description, categories[description], category)
+			}
+		}
+	}
+}
+
+func TestOfflineAnswersDoNotDependOnPromptWording(t *testing.T) {
+	content := "---\ncategories:\n  - Export\n  - Admin\ncategory_fallback: Other\n---\n" + testStoriesFile
+	svc, _, _ := newTestService(t, content, "")
+	overrides := make(map[domain.PromptName]domain.PromptTemplate)
+	for _, name := range []domain.PromptName{domain.PromptCategorize, domain.PromptCategorizeBatch, domain.PromptGenerate, domain.PromptPrioritize} {
+		overrides[name] = domain.PromptTemplate{Name: name, Source: "dir", Text: "Do what the schema asks."}
+	}
+	svc.SetPromptOverrides(overrides)
+
+	if _, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{BatchSize: 3}); err != nil {
+		t.Fatalf("CategorizeAllStories() error = %v", err)
+	}
+	generated, err := svc.GenerateNewStories(t.Context(), application.GenerateOptions{Count: 2, Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}})
+	if err != nil {
+		t.Fatalf("GenerateNewStories() error = %v", err)
+	}
+	if len(generated.Stories) != 2 {
+		t.Errorf("generated %d stories, want 2", len(generated.Stories))
+	}
+	if _, err := svc.PrioritizeStories(t.Context(), application.PrioritizeOptions{Scheme: application.PrioritySchemeMoSCoW, SkipConfirm: true}); err != nil {
+		t.Fatalf("PrioritizeStories() error = %v", err)
+	}
+
+	var got []string
+	for _, story := range readStories(t, svc).Stories {
+		got = append(got, story.Category+"/"+string(story.Priority))
+	}
+	if want := "Admin/Must,Export/Should,Other/Could,Other/Won't,Other/Must"; strings.Join(got, ",") != want {
+		t.Errorf("categories and priorities = %v, want %s", got, want)
+	}
+}
+
+func TestCategorizeAllStoriesStopsOnFatalError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := os.WriteFile(path, []byte(testStoriesFile), 0644); err != nil {
+		t.Fatalf("failed to write story file: %v", err)
+	}
+	authFailed := &domain.LLMError{Kind: domain.ErrLLMAuthFailed}
+	svc := application.NewUserStoryService(failingLLMService{err: authFailed}, path, adapters.NewLocalFileReader())
+
+	_, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{Concurrency: 2})
+	if !errors.Is(err, domain.ErrLLMAuthFailed) {
+		t.Fatalf("CategorizeAllStories() error = %v, want auth failed", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != testStoriesFile {
+		t.Errorf("file changed after a fatal error:\n%s", content)
+	}
+
+	// Other failures leave the story uncategorized and carry on.
+	svc = application.NewUserStoryService(failingLLMService{err: &domain.LLMError{Kind: domain.ErrLLMEmptyResponse}}, path, adapters.NewLocalFileReader())
+	if _, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{}); err != nil {
+		t.Fatalf("CategorizeAllStories() error = %v", err)
+	}
+	for _, story := range readStories(t, svc).Stories {
+		if story.Category != "Uncategorized" {
+			t.Errorf("story %s category = %q, want Uncategorized", story.ID, story.Category)
+		}
+	}
+}
+
+// cancellingLLMService answers like the offline service and cancels the context once
+// it has answered the given number of simple requests.
+type cancellingLLMService struct {
+	*adapters.OfflineLLMService
+	cancel  context.CancelFunc
+	answers int
+}
+
+func (c *cancellingLLMService) AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error) {
+	if c.answers == 0 {
+		c.cancel()
+		return domain.LLMResponse{}, ctx.Err()
+	}
+	c.answers--
+	return c.OfflineLLMService.AskSimple(ctx, input)
+}
+
+func TestCategorizeAllStoriesSavesProgressWhenCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stories.md")
+	content := "- As a user, I want to fix the login bug [Category: Old] [UUID: aaa11111-0000-0000-0000-000000000001]\n" +
+		"- As a user, I want to ban users [Category: Old] [UUID: bbb22222-0000-0000-0000-000000000002]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write story file: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	offline, _ := adapters.NewOfflineLLMService(domain.LLMProviderConfig{})
+	llm := &cancellingLLMService{OfflineLLMService: offline, cancel: cancel, answers: 1}
+	svc := application.NewUserStoryService(llm, path, adapters.NewLocalFileReader())
+
+	_, err := svc.CategorizeAllStories(ctx, application.CategorizeOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CategorizeAllStories() error = %v, want context.Canceled", err)
+	}
+	var got []string
+	for _, story := range readStories(t, svc).Stories {
+		got = append(got, story.ID[:3]+"="+story.Category)
+	}
+	if want := "aaa=Bug,bbb=Old"; strings.Join(got, ",") != want {
+		t.Errorf("stories after cancellation = %v, want %s", got, want)
+	}
+}
+
+func TestGenerateNewStoriesSavesAcceptedWhenCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := os.WriteFile(path, []byte(testStoriesFile), 0644); err != nil {
+		t.Fatalf("failed to write story file: %v", err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	offline, _ := adapters.NewOfflineLLMService(domain.LLMProviderConfig{})
+	// The first generated story is categorized, the context is cancelled while categorizing
+	// the second, which is dropped rather than saved with the fallback category.
+	llm := &cancellingLLMService{OfflineLLMService: offline, cancel: cancel, answers: 1}
+	svc := application.NewUserStoryService(llm, path, adapters.NewLocalFileReader())
+
+	_, err := svc.GenerateNewStories(ctx, application.GenerateOptions{Count: 3, Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateNewStories() error = %v, want context.Canceled", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 4 || stories[3].Category != "Feature" {
+		t.Errorf("stories after cancellation = %+v", stories[3:])
+	}
+}
+
+// generatingLLMService answers like the offline service but generates the given stories.
+type generatingLLMService struct {
+	*adapters.OfflineLLMService
+	stories []string
+}
+
+func (g *generatingLLMService) AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error) {
+	if input.SchemaName != "GenerateNewUserStories" {
+		return g.OfflineLLMService.AskAdvanced(ctx, input)
+	}
+	content, err := json.Marshal(application.GeneratedStoriesResponse{NewUserStories: g.stories})
+	return domain.LLMResponse{Content: string(content)}, err
+}
+
+func TestGenerateNewStoriesSkipsInvalidDescriptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := os.WriteFile(path, []byte(testStoriesFile), 0644); err != nil {
+		t.Fatalf("failed to write story file: %v", err)
+	}
+	offline, _ := adapters.NewOfflineLLMService(domain.LLMProviderConfig{})
+	llm := &generatingLLMService{OfflineLLMService: offline, stories: []string{
+		"As a user, I want [links] in stories",
+		"As a user, I want\nline breaks",
+		"  ",
+		" As a user, I want a valid story ",
+	}}
+	svc := application.NewUserStoryService(llm, path, adapters.NewLocalFileReader())
+
+	result, err := svc.GenerateNewStories(t.Context(), application.GenerateOptions{Count: 4, Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}})
+	if err != nil {
+		t.Fatalf("GenerateNewStories() error = %v", err)
+	}
+	if got := storyDescriptions(result.Stories); len(got) != 1 || got[0] != "As a user, I want a valid story" {
+		t.Errorf("generated stories = %v", got)
+	}
+	if got := len(readStories(t, svc).Stories); got != 4 {
+		t.Errorf("got %d stories, want 4", got)
+	}
+}
+
+func TestPromptStopsWhenCancelled(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	answers, writer := io.Pipe()
+	defer writer.Close()
+	svc.SetInteraction(adapters.NewConsoleInteraction(answers, io.Discard))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	description := "Changed"
+	if _, err := svc.EditUserStory(ctx, "aaa1", application.StoryChanges{Description: &description}, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EditUserStory() error = %v, want context.Canceled", err)
+	}
+	if got := readStories(t, svc).Stories[0].Description; got != "As a user, I want to log in" {
+		t.Errorf("cancelled edit changed description to %q", got)
+	}
+}
+
+func TestMergeCategories(t *testing.T) {
+	content := "---\ncategories:\n  - Auth\n  - Bug\n  - Admin\n---\n" + testStoriesFile
+	svc, _, _ := newTestService(t, content, "")
+	if _, err := svc.RenameCategory(t.Context(), "auth", "Accounts", true); err != nil {
+		t.Fatalf("RenameCategory() error = %v", err)
+	}
+	if _, err := svc.MergeCategories(t.Context(), []string{"Bug", "Admin"}, "Accounts", false); err != nil {
+		t.Fatalf("MergeCategories() error = %v", err)
+	}
+	markdownFile := readStories(t, svc)
+	for _, story := range markdownFile.Stories {
+		if story.Category != "Accounts" {
+			t.Errorf("%q has category %q, want Accounts", story.Description, story.Category)
+		}
+	}
+	taxonomy, err := domain.ParseTaxonomy(markdownFile.Metadata)
+	if err != nil {
+		t.Fatalf("ParseTaxonomy() error = %v", err)
+	}
+	if got := strings.Join(taxonomy.Names(), ","); got != "Accounts,Bug,Admin" {
+		t.Errorf("taxonomy = %s, want only the renamed category changed", got)
+	}
+
+	if _, err := svc.RenameCategory(t.Context(), "Missing", "Other", false); err == nil {
+		t.Error("RenameCategory() of an unused category did not fail")
+	}
+	if _, err := svc.MergeCategories(t.Context(), []string{"Accounts"}, "[bad]", false); err == nil {
+		t.Error("MergeCategories() accepted a category name with brackets")
+	}
+}
+
+func TestSummarizeStories(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	summary, err := svc.SummarizeStories(t.Context())
+	if err != nil {
+		t.Fatalf("SummarizeStories() error = %v", err)
+	}
+	markdownFile := readStories(t, svc)
+	if !strings.Contains(markdownFile.Summary, "3 user stories") || summary.Text != markdownFile.Summary {
+		t.Errorf("Summary = %q, returned %q", markdownFile.Summary, summary.Text)
+	}
+	if len(markdownFile.Stories) != 3 {
+		t.Errorf("got %d stories after summarizing, want 3", len(markdownFile.Stories))
+	}
+}
+
+func TestSummarizeStoriesUsesFixture(t *testing.T) {
+	svc, llm, _ := newTestService(t, "- Only story [Category: Misc] [UUID: ddd44444-0000-0000-0000-000000000004]\n", "")
+	llm.AddFixture(domain.PromptHash(domain.ModelTypeSimple,
+		"Please create a summary of what the project is based on the user stories which are input. Write about what is is based on the user stories but also what it could become. Do not include any preamble like 'Here is the summary:'.",
+		"Only story", ""), "A fixture summary.")
+	if _, err := svc.SummarizeStories(t.Context()); err != nil {
+		t.Fatalf("SummarizeStories() error = %v", err)
+	}
+	if got := readStories(t, svc).Summary; got != "A fixture summary." {
+		t.Errorf("Summary = %q, want the fixture response", got)
+	}
+}
+
+func TestSummarizeStoriesUsesPromptOverrides(t *testing.T) {
+	content := "---\nlanguage: German\nprompts:\n  summarize: Summarize in {{.Metadata.language}}.\n---\n- Only story [Category: Misc] [UUID: ddd44444-0000-0000-0000-000000000004]\n"
+	svc, llm, _ := newTestService(t, content, "")
+	llm.AddFixture(domain.PromptHash(domain.ModelTypeSimple, "Summarize in German.", "Only story", ""), "Eine Zusammenfassung.")
+	// The front matter wins over the prompts directory.
+	svc.SetPromptOverrides(map[domain.PromptName]domain.PromptTemplate{
+		domain.PromptSummarize: {Name: domain.PromptSummarize, Source: "dir", Text: "Summarize in English."},
+	})
+	if _, err := svc.SummarizeStories(t.Context()); err != nil {
+		t.Fatalf("SummarizeStories() error = %v", err)
+	}
+	if got := readStories(t, svc).Summary; got != "Eine Zusammenfassung." {
+		t.Errorf("Summary = %q, want the response to the front matter prompt", got)
+	}
+
+	prompts, err := svc.EffectivePrompts()
+	if err != nil {
+		t.Fatalf("EffectivePrompts() error = %v", err)
+	}
+	if prompt, _ := prompts.Template(domain.PromptSummarize); prompt.Source != "front matter" {
+		t.Errorf("summarize prompt source = %q, want front matter", prompt.Source)
+	}
+	if prompt, _ := prompts.Template(domain.PromptGenerate); prompt.Source != domain.PromptSourceBuiltIn {
+		t.Errorf("generate prompt source = %q, want built-in", prompt.Source)
+	}
+}
+
+func TestListUserStories(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	tests := []struct {
+		query application.StoryQuery
+		want  []string
+	}{
+		{application.StoryQuery{}, []string{"As a user, I want to log in", "As a user, I want to fix the broken export", "As an admin, I want to ban users"}},
+		{application.StoryQuery{Categories: []string{"Auth"}}, []string{"As a user, I want to log in"}},
+		{application.StoryQuery{SortBy: application.SortByDescription}, []string{"As a user, I want to fix the broken export", "As a user, I want to log in", "As an admin, I want to ban users"}},
+		{application.StoryQuery{Text: "nothing matches this"}, []string{}},
+	}
+	for _, tt := range tests {
+		list, err := svc.ListUserStories(t.Context(), tt.query)
+		if err != nil {
+			t.Errorf("ListUserStories(%+v) error = %v", tt.query, err)
+			continue
+		}
+		if got := storyDescriptions(list.Stories); list.Total != 3 || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListUserStories(%+v) = %v of %d, want %v of 3", tt.query, got, list.Total, tt.want)
+		}
+	}
+	if _, err := svc.ListUserStories(t.Context(), application.StoryQuery{Text: "(", TextIsRegex: true}); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+func TestExportStories(t *testing.T) {
+	svc, _, path := newTestService(t, testStoriesFile, "")
+	outPath := filepath.Join(filepath.Dir(path), "export.json")
+	if _, err := svc.ExportStories(t.Context(), domain.ExportFormatJSON, outPath, application.StoryQuery{Categories: []string{"Bug"}}); err != nil {
+		t.Fatalf("ExportStories() error = %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	var exported struct {
+		Stories []domain.UserStory `json:"stories"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+	if len(exported.Stories) != 1 || exported.Stories[0].Category != "Bug" {
+		t.Errorf("exported stories = %+v", exported.Stories)
+	}
+
+	// Without a path the export is returned instead of written.
+	result, err := svc.ExportStories(t.Context(), domain.ExportFormatCSV, "", application.StoryQuery{Categories: []string{"Bug"}})
+	if err != nil {
+		t.Fatalf("ExportStories() error = %v", err)
+	}
+	if result.Stories != 1 || !strings.Contains(result.Content, "As a user, I want to fix the broken export") {
+		t.Errorf("ExportStories() without a path = %+v", result)
+	}
+}
+
+func TestImportStories(t *testing.T) {
+	svc, _, path := newTestService(t, testStoriesFile, "")
+	importPath := filepath.Join(filepath.Dir(path), "backlog.txt")
+	backlog := "As a user, I want to log in\nAs a user, I want notifications\n"
+	if err := os.WriteFile(importPath, []byte(backlog), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	if _, err := svc.ImportStories(t.Context(), importPath, domain.ImportFormatText, nil, application.DuplicatesSkip); err != nil {
+		t.Fatalf("ImportStories() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 4 || stories[3].Description != "As a user, I want notifications" || stories[3].Category != "Uncategorized" {
+		t.Errorf("stories after skip import = %v", storyDescriptions(stories))
+	}
+
+	if _, err := svc.ImportStories(t.Context(), importPath, domain.ImportFormatText, nil, application.DuplicatesFlag); err != nil {
+		t.Fatalf("ImportStories() error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 6 {
+		t.Errorf("got %d stories after flag import, want 6", got)
+	}
+}
+
+func TestImportStoriesSkipsInvalidRows(t *testing.T) {
+	svc, _, path := newTestService(t, testStoriesFile, "")
+	importPath := filepath.Join(filepath.Dir(path), "backlog.csv")
+	backlog := "description,category\n" +
+		"\"As a user, I want [links] in stories\",Feature\n" +
+		"\"As a user, I want\nline breaks\",Feature\n" +
+		"\"As a user, I want valid rows\",Bad [Category]\n" +
+		"\"As a user, I want to import CSV\", Feature / Import \n"
+	if err := os.WriteFile(importPath, []byte(backlog), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	result, err := svc.ImportStories(t.Context(), importPath, domain.ImportFormatCSV, nil, application.DuplicatesSkip)
+	if err != nil {
+		t.Fatalf("ImportStories() error = %v", err)
+	}
+	if result.Imported != 1 || len(result.Skipped) != 3 {
+		t.Fatalf("ImportStories() = %+v, want 1 imported and 3 skipped", result)
+	}
+	for i, want := range []string{"entry 1: description", "entry 2: description", "category name"} {
+		if !strings.Contains(result.Skipped[i], want) {
+			t.Errorf("Skipped[%d] = %q, want it to mention %q", i, result.Skipped[i], want)
+		}
+	}
+	stories := readStories(t, svc).Stories
+	if last := stories[len(stories)-1]; last.Description != "As a user, I want to import CSV" || last.Category != "Feature/Import" {
+		t.Errorf("imported story = %+v", last)
+	}
+}
+
+func TestImportStoriesCreatesFile(t *testing.T) {
+	svc, _, path := newTestService(t, "", "")
+	importPath := filepath.Join(filepath.Dir(path), "backlog.txt")
+	if err := os.WriteFile(importPath, []byte("- First story\n- Second story\n"), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+	if _, err := svc.ImportStories(t.Context(), importPath, domain.ImportFormatText, nil, application.DuplicatesSkip); err != nil {
+		t.Fatalf("ImportStories() error = %v", err)
+	}
+	if got := storyDescriptions(readStories(t, svc).Stories); len(got) != 2 || got[0] != "First story" {
+		t.Errorf("imported stories = %v", got)
+	}
+}
+
+func TestGenerateNewStories(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	opts := application.GenerateOptions{Count: 3, Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}}
+	if _, err := svc.GenerateNewStories(t.Context(), opts); err != nil {
+		t.Fatalf("GenerateNewStories() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 6 {
+		t.Fatalf("got %d stories, want 6", len(stories))
+	}
+	for _, story := range stories[3:] {
+		if !strings.Contains(story.Description, "offline generated feature") || story.Category != "Feature" || story.Status != domain.StatusProposed {
+			t.Errorf("generated story = %+v", story)
+		}
+	}
+}
+
+func TestGenerateNewStoriesUnattended(t *testing.T) {
+	opts := application.GenerateOptions{Count: 2, Duplicates: application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}, DryRun: true}
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	svc.SetInteraction(adapters.NewConsoleInteraction(nil, io.Discard))
+	if _, err := svc.GenerateNewStories(t.Context(), opts); err != nil {
+		t.Fatalf("GenerateNewStories() dry run error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 3 {
+		t.Errorf("dry run: got %d stories, want 3", got)
+	}
+
+	opts.DryRun, opts.AcceptAll = false, true
+	if _, err := svc.GenerateNewStories(t.Context(), opts); err == nil {
+		t.Error("expected an error for accepting without a reviewer")
+	}
+	opts.Reviewer = "ci [bot]"
+	if _, err := svc.GenerateNewStories(t.Context(), opts); err == nil {
+		t.Error("expected an error for a reviewer with brackets")
+	}
+	opts.Reviewer = "ci"
+	if _, err := svc.GenerateNewStories(t.Context(), opts); err != nil {
+		t.Fatalf("GenerateNewStories() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 5 {
+		t.Fatalf("got %d stories, want 5", len(stories))
+	}
+	for _, story := range stories[3:] {
+		if story.Status != domain.StatusAccepted || story.DecidedBy != "ci" || story.DecidedAt == nil {
+			t.Errorf("accepted story = %+v", story)
+		}
+	}
+}
+
+func TestNonInteractivePromptFails(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "y\n")
+	svc.SetInteraction(adapters.NewConsoleInteraction(nil, io.Discard))
+	if _, err := svc.RemoveUserStory(t.Context(), "aaa1", false); !errors.Is(err, ports.ErrInputRequired) {
+		t.Fatalf("RemoveUserStory() error = %v, want ErrInputRequired", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 3 {
+		t.Errorf("got %d stories, want 3", got)
+	}
+	if _, err := svc.RemoveUserStory(t.Context(), "aaa1", true); err != nil {
+		t.Errorf("RemoveUserStory() with confirmation skipped error = %v", err)
+	}
+}
+
+func TestServiceWithoutInteractionIsUnattended(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := os.WriteFile(path, []byte(testStoriesFile), 0644); err != nil {
+		t.Fatalf("failed to write story file: %v", err)
+	}
+	llm, _ := adapters.NewOfflineLLMService(domain.LLMProviderConfig{})
+	svc := application.NewUserStoryService(llm, path, adapters.NewLocalFileReader())
+
+	if _, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "server"}); !errors.Is(err, ports.ErrInputRequired) {
+		t.Errorf("ReviewProposedStories() error = %v, want ErrInputRequired", err)
+	}
+	result, err := svc.CategorizeAllStories(t.Context(), application.CategorizeOptions{})
+	if err != nil {
+		t.Fatalf("CategorizeAllStories() error = %v", err)
+	}
+	if len(result.Stories) != 3 {
+		t.Errorf("CategorizeAllStories() = %+v", result)
+	}
+}
+
+func TestReviewProposedStories(t *testing.T) {
+	content := testStoriesFile +
+		"- As a user, I want dark mode [Category: UI] [Status: Proposed] [UUID: ddd44444-0000-0000-0000-000000000004]\n" +
+		"- As a user, I want a mobile app [Category: UI] [Status: Proposed] [UUID: eee55555-0000-0000-0000-000000000005]\n" +
+		"- As a user, I want themes [Category: UI] [Status: Proposed] [UUID: fff66666-0000-0000-0000-000000000006]\n"
+	// Story 1 of the queue (the proposed bug fix) is skipped, dark mode is edited, moved
+	// and accepted, the mobile app is declined and the review is quit before themes.
+	answers := "s\ne\nAs a user, I want a dark theme\nr\nAppearance\na\nx\nd\nq\n"
+	svc, _, _ := newTestService(t, content, answers)
+	if _, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "alex"}); err != nil {
+		t.Fatalf("ReviewProposedStories() error = %v", err)
+	}
+
+	stories := readStories(t, svc).Stories
+	if stories[1].Status != domain.StatusProposed || stories[1].DecidedBy != "" {
+		t.Errorf("skipped story = %+v", stories[1])
+	}
+	darkMode := stories[3]
+	if darkMode.Description != "As a user, I want a dark theme" || darkMode.Category != "Appearance" || darkMode.Status != domain.StatusAccepted {
+		t.Errorf("accepted story = %+v", darkMode)
+	}
+	if darkMode.DecidedBy != "alex" || darkMode.DecidedAt == nil || time.Since(*darkMode.DecidedAt) > time.Minute {
+		t.Errorf("decision not recorded: by %q at %v", darkMode.DecidedBy, darkMode.DecidedAt)
+	}
+	if stories[4].Status != domain.StatusDeclined || stories[4].DecidedBy != "alex" {
+		t.Errorf("declined story = %+v", stories[4])
+	}
+	if stories[5].Status != domain.StatusProposed {
+		t.Errorf("story after quitting = %+v", stories[5])
+	}
+
+	if _, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "[bot]"}); err == nil {
+		t.Error("ReviewProposedStories() accepted a reviewer with brackets")
+	}
+}
+
+func TestReviewFollowsWorkflow(t *testing.T) {
+	content := `---
+workflow:
+  states: [proposed, approved, accepted, rejected]
+  transitions:
+    proposed: [accepted]
+---
+**UI**
+- As a user, I want dark mode [Category: UI] [Status: proposed] [UUID: ddd44444-0000-0000-0000-000000000004]
+- As a user, I want themes [Category: UI] [Status: proposed] [UUID: eee55555-0000-0000-0000-000000000005]
+`
+	// Declining is refused, as the workflow has no Declined state, so the first story is
+	// accepted instead; the second is skipped.
+	svc, _, _ := newTestService(t, content, "d\na\ns\n")
+	result, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "alex"})
+	if err != nil {
+		t.Fatalf("ReviewProposedStories() error = %v", err)
+	}
+	if result.Accepted != 1 || result.Declined != 0 || result.Remaining != 1 {
+		t.Errorf("result = %+v, want 1 accepted and 1 remaining", result)
+	}
+	if got := readStories(t, svc).Stories[0].Status; got != "accepted" {
+		t.Errorf("Status = %q, want the workflow's accepted state", got)
+	}
+
+	content = strings.Replace(content, "proposed: [accepted]", "proposed: [approved]", 1)
+	svc, _, _ = newTestService(t, content, "a\ns\ns\n")
+	if result, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "alex"}); err != nil || result.Accepted != 0 {
+		t.Errorf("ReviewProposedStories() = %+v, %v, want the forbidden transition refused", result, err)
+	}
+
+	svc, _, _ = newTestService(t, "---\nworkflow:\n  states: [Todo, Done]\n---\n", "")
+	if _, err := svc.ReviewProposedStories(t.Context(), application.ReviewOptions{Reviewer: "alex"}); err == nil {
+		t.Error("ReviewProposedStories() expected an error for a workflow without a proposed state")
+	}
+}
+
+func TestAddUserStoryPropose(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	if _, err := svc.AddUserStory(t.Context(), "As a user, I want to export invoices", application.AddStoryOptions{Force: true, Propose: true}); err != nil {
+		t.Fatalf("AddUserStory() error = %v", err)
+	}
+	if got := readStories(t, svc).Stories[3].Status; got != domain.StatusProposed {
+		t.Errorf("Status = %q, want Proposed", got)
+	}
+}
+
+func TestGeneratePossibleCategories(t *testing.T) {
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	categories := svc.GeneratePossibleCategories(t.Context(), readStories(t, svc).Stories)
+	if strings.Join(categories, ",") != "Bug,Feature,Chore,Technical Debt" {
+		t.Errorf("GeneratePossibleCategories() = %v", categories)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	svc, llm, _ := newTestService(t, testStoriesFile, "")
+	stories := readStories(t, svc).Stories
+
+	matches, err := svc.FindDuplicates(t.Context(), "As an admin, I want to ban users", stories, application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Story.ID != stories[2].ID {
+		t.Errorf("FindDuplicates() = %+v", matches)
+	}
+
+	// The offline model finds no semantic duplicates unless a fixture says otherwise.
+	opts := application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold, Semantic: true}
+	matches, err = svc.FindDuplicates(t.Context(), "As a user, I want to log in quickly", stories, opts)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("FindDuplicates() without fixture = %+v", matches)
+	}
+
+	// A reworded duplicate without a word in common still reaches the LLM, with the
+	// other stories as candidates as well.
+	var candidates strings.Builder
+	for _, story := range stories {
+		candidates.WriteString("- ID " + story.ID + ": " + story.Description + "\n")
+	}
+	llm.AddFixture(domain.PromptHash(domain.ModelTypeSimple,
+		"Decide which of the existing user stories describe the same functionality as the new user story, even if worded differently. Only return the IDs of real duplicates; return an empty list if there are none.",
+		"New user story:\nSigning on takes one click\n\nExisting user stories:\n"+candidates.String(),
+		"FindDuplicateUserStories"), `{"duplicate_ids":["`+stories[0].ID+`"]}`)
+	matches, err = svc.FindDuplicates(t.Context(), "Signing on takes one click", stories, opts)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(matches) != 1 || !matches[0].Semantic || matches[0].Story.ID != stories[0].ID {
+		t.Errorf("FindDuplicates() with fixture = %+v", matches)
+	}
+}
+
+func TestDedupeStories(t *testing.T) {
+	content := testStoriesFile + "- As an admin I want to ban users [Category: Uncategorized] [Status: Accepted] [UUID: eee55555-0000-0000-0000-000000000005]\n"
+	opts := application.DuplicateCheckOptions{Threshold: application.DefaultDuplicateThreshold}
+
+	svc, _, _ := newTestService(t, content, "")
+	if _, err := svc.DedupeStories(t.Context(), opts, false); err != nil {
+		t.Fatalf("DedupeStories() error = %v", err)
+	}
+	if got := len(readStories(t, svc).Stories); got != 4 {
+		t.Errorf("report only: got %d stories, want 4", got)
+	}
+
+	svc, _, _ = newTestService(t, content, "1\n")
+	if _, err := svc.DedupeStories(t.Context(), opts, true); err != nil {
+		t.Fatalf("DedupeStories() error = %v", err)
+	}
+	stories := readStories(t, svc).Stories
+	if len(stories) != 3 {
+		t.Fatalf("merge: got %d stories, want 3", len(stories))
+	}
+	kept := stories[2]
+	if kept.ID != "ccc33333-0000-0000-0000-000000000003" || kept.Status != domain.StatusAccepted {
+		t.Errorf("kept story = %+v, want the first story with the merged status", kept)
+	}
+}
+
+func TestPushProject(t *testing.T) {
+	var pushed domain.Project
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/projects" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&pushed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	t.Setenv("API_HOST", server.URL)
+
+	svc, _, _ := newTestService(t, testStoriesFile, "Test Project\n")
+	if _, err := svc.PushProject(t.Context(), application.PushOptions{DryRun: true, ProjectName: "Dry"}); err != nil {
+		t.Fatalf("PushProject() dry run error = %v", err)
+	}
+	if pushed.ID != "" || readStories(t, svc).Metadata["project_id"] != nil {
+		t.Errorf("dry run pushed %+v", pushed)
+	}
+	if _, err := svc.PushProject(t.Context(), application.PushOptions{}); err != nil {
+		t.Fatalf("PushProject() error = %v", err)
+	}
+	if pushed.Name != "Test Project" || len(pushed.UserStories) != 3 {
+		t.Errorf("pushed project = %+v", pushed)
+	}
+	metadata := readStories(t, svc).Metadata
+	if metadata["project_id"] != pushed.ID || metadata["project_name"] != "Test Project" {
+		t.Errorf("metadata = %v", metadata)
+	}
+
+	svc, _, _ = newTestService(t, testStoriesFile, "\n")
+	if _, err := svc.PushProject(t.Context(), application.PushOptions{}); err == nil {
+		t.Error("expected an error for an empty project name")
+	}
+
+	svc, _, _ = newTestService(t, testStoriesFile, "")
+	svc.SetInteraction(adapters.NewConsoleInteraction(nil, io.Discard))
+	if _, err := svc.PushProject(t.Context(), application.PushOptions{}); !errors.Is(err, ports.ErrInputRequired) {
+		t.Errorf("PushProject() error = %v, want ErrInputRequired", err)
+	}
+	if _, err := svc.PushProject(t.Context(), application.PushOptions{ProjectName: "CI Project"}); err != nil {
+		t.Fatalf("PushProject() error = %v", err)
+	}
+	if pushed.Name != "CI Project" {
+		t.Errorf("pushed project name = %q, want CI Project", pushed.Name)
+	}
+}
+
+func TestRemoteProjects(t *testing.T) {
+	project := domain.Project{ID: "p1", Name: "Remote", UserStories: []domain.UserStory{{ID: "s1", Description: "Story", Category: "Misc"}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/projects":
+			json.NewEncoder(w).Encode([]domain.Project{project})
+		case "/api/projects/p1":
+			json.NewEncoder(w).Encode(project)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("API_HOST", server.URL)
+
+	svc, _, _ := newTestService(t, testStoriesFile, "")
+	projects, err := svc.ListProjectsRemote(t.Context())
+	if err != nil || len(projects) != 1 || projects[0].Name != "Remote" {
+		t.Errorf("ListProjectsRemote() = %+v, %v", projects, err)
+	}
+	got, err := svc.GetProjectRemote(t.Context(), "p1")
+	if err != nil || !reflect.DeepEqual(*got, project) {
+		t.Errorf("GetProjectRemote() = %+v, %v", got, err)
+	}
+	if _, err := svc.GetProjectRemote(t.Context(), "missing"); err == nil {
+		t.Error("expected an error for a missing project")
+	}
+	if _, err := svc.GetProjectRemote(t.Context(), ""); err == nil {
+		t.Error("expected an error for an empty id")
+	}
+}
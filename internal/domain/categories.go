@@ -0,0 +1,271 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CategoryCount is the number of stories in a category.
+type CategoryCount struct {
+	Name    string `json:"name"`
+	Stories int    `json:"stories"`
+	// Declared is set when the category is part of the file's taxonomy.
+	Declared bool `json:"declared"`
+}
+
+// storyCategory returns the category a story is written under.
+func storyCategory(story UserStory) string {
+	if story.Category == "" {
+		return DefaultFallbackCategory
+	}
+	return story.Category
+}
+
+// CategoryCounts counts the stories per category, in the order the categories appear in
+// the file. Categories of taxonomy, which may be nil, that have no stories follow with a
+// count of zero.
+func (m *MarkdownFile) CategoryCounts(taxonomy *Taxonomy) []CategoryCount {
+	var counts []CategoryCount
+	index := make(map[string]int)
+	for _, story := range m.Stories {
+		category := storyCategory(story)
+		i, ok := index[category]
+		if !ok {
+			i = len(counts)
+			index[category] = i
+			counts = append(counts, CategoryCount{Name: category})
+		}
+		counts[i].Stories++
+	}
+	if taxonomy == nil {
+		return counts
+	}
+
+	for _, name := range taxonomy.allowed() {
+		found := false
+		for i := range counts {
+			if strings.EqualFold(counts[i].Name, name) {
+				counts[i].Declared = true
+				found = true
+			}
+		}
+		if !found && name != taxonomy.Fallback {
+			counts = append(counts, CategoryCount{Name: name, Declared: true})
+		}
+	}
+	return counts
+}
+
+// RecategorizeStories moves the stories of the source categories, compared without regard
+// to case, into the category into and returns how many stories changed. Stories in nested
+// categories move along, so renaming Auth files "Auth/Password Reset" under the new name.
+func (m *MarkdownFile) RecategorizeStories(sources []string, into string) int {
+	changed := 0
+	for i := range m.Stories {
+		category := storyCategory(m.Stories[i])
+		moved, ok := movedCategory(category, sources, into)
+		if !ok || moved == category {
+			continue
+		}
+		m.Stories[i].Category = moved
+		changed++
+	}
+	return changed
+}
+
+// movedCategory returns where category goes when the source categories are moved into
+// into: the path prefix of the first source it is within is replaced by into. A source
+// that into is nested below only moves its own stories, not its other nested categories.
+func movedCategory(category string, sources []string, into string) (string, bool) {
+	category = NormalizeCategoryPath(category)
+	for _, source := range sources {
+		source = NormalizeCategoryPath(source)
+		if !CategoryWithin(category, source) {
+			continue
+		}
+		if strings.EqualFold(category, source) {
+			return into, true
+		}
+		if CategoryWithin(into, source) {
+			continue
+		}
+		return NormalizeCategoryPath(into + category[len(source):]), true
+	}
+	return category, false
+}
+
+// ReplaceTaxonomyCategories replaces the source categories, and the categories nested
+// below them, in the taxonomy of the front matter by into. The first source keeps its place
+// and description under the new name, unless into is already declared; the other sources
+// are removed. A fallback that names a source is changed as well. It reports whether the
+// taxonomy changed.
+func (m *MarkdownFile) ReplaceTaxonomyCategories(sources []string, into string) (bool, error) {
+	if _, err := ParseTaxonomy(m.Metadata); err != nil {
+		return false, err
+	}
+	changed := false
+	if fallback, ok := m.Metadata["category_fallback"].(string); ok {
+		if moved, ok := movedCategory(fallback, sources, into); ok && moved != strings.TrimSpace(fallback) {
+			m.Metadata["category_fallback"] = moved
+			changed = true
+		}
+	}
+	entries, ok := m.Metadata["categories"].([]interface{})
+	if !ok {
+		return changed, nil
+	}
+
+	declared := make(map[string]bool)
+	for _, entry := range entries {
+		declared[strings.ToLower(NormalizeCategoryPath(taxonomyEntryName(entry)))] = true
+	}
+	var updated []interface{}
+	for _, entry := range entries {
+		name := taxonomyEntryName(entry)
+		moved, ok := movedCategory(name, sources, into)
+		if !ok || strings.EqualFold(moved, NormalizeCategoryPath(name)) {
+			updated = append(updated, entry)
+			continue
+		}
+		changed = true
+		if declared[strings.ToLower(moved)] {
+			continue
+		}
+		declared[strings.ToLower(moved)] = true
+		switch value := entry.(type) {
+		case map[string]interface{}:
+			value["name"] = moved
+			updated = append(updated, value)
+		default:
+			updated = append(updated, moved)
+		}
+	}
+	m.Metadata["categories"] = updated
+	return changed, nil
+}
+
+func taxonomyEntryName(entry interface{}) string {
+	switch value := entry.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case map[string]interface{}:
+		name, _ := value["name"].(string)
+		return strings.TrimSpace(name)
+	}
+	return ""
+}
+
+// ValidateCategoryName checks a category name given on the command line and normalizes
+// it when it is a nested path.
+func ValidateCategoryName(name string) (string, error) {
+	name = NormalizeCategoryPath(name)
+	if name == "" {
+		return "", fmt.Errorf("category name must not be empty")
+	}
+	if strings.ContainsAny(name, "[]\n") {
+		return "", fmt.Errorf("category name '%s' must not contain brackets or line breaks", name)
+	}
+	return name, nil
+}
+
+// CategoryPathSeparator separates the levels of a nested category such as
+// "Auth/Password Reset", where Auth is the epic and Password Reset a category within it.
+const CategoryPathSeparator = "/"
+
+// CategoryPathSegments splits a category path into its trimmed, non-empty levels.
+func CategoryPathSegments(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, CategoryPathSeparator) {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// NormalizeCategoryPath trims the levels of a category path, so "Auth / Password Reset"
+// and "Auth/Password Reset" name the same category.
+func NormalizeCategoryPath(path string) string {
+	return strings.Join(CategoryPathSegments(path), CategoryPathSeparator)
+}
+
+// CategoryWithin reports whether category is ancestor or nested below it, ignoring case.
+func CategoryWithin(category string, ancestor string) bool {
+	category, ancestor = NormalizeCategoryPath(category), NormalizeCategoryPath(ancestor)
+	if strings.EqualFold(category, ancestor) {
+		return true
+	}
+	prefix := ancestor + CategoryPathSeparator
+	return len(category) > len(prefix) && strings.EqualFold(category[:len(prefix)], prefix)
+}
+
+// CategoryNode is one level of the category tree: the stories filed directly under its
+// path and the nested categories below it.
+type CategoryNode struct {
+	Name     string
+	Path     string
+	Stories  []UserStory
+	Children []*CategoryNode
+}
+
+// BuildCategoryTree arranges stories by their category path. The returned root has no
+// name; nodes keep the order in which their categories first appear.
+func BuildCategoryTree(stories []UserStory) *CategoryNode {
+	root := &CategoryNode{}
+	for _, story := range stories {
+		node := root
+		for _, segment := range CategoryPathSegments(storyCategory(story)) {
+			node = node.child(segment)
+		}
+		node.Stories = append(node.Stories, story)
+	}
+	return root
+}
+
+func (n *CategoryNode) child(name string) *CategoryNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	path := name
+	if n.Path != "" {
+		path = n.Path + CategoryPathSeparator + name
+	}
+	child := &CategoryNode{Name: name, Path: path}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// Count returns the number of stories in the node and all nodes below it.
+func (n *CategoryNode) Count() int {
+	count := len(n.Stories)
+	for _, child := range n.Children {
+		count += child.Count()
+	}
+	return count
+}
+
+// SortChildren orders the nested categories of every level by name.
+func (n *CategoryNode) SortChildren(descending bool) {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		if descending {
+			return n.Children[i].Name > n.Children[j].Name
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, child := range n.Children {
+		child.SortChildren(descending)
+	}
+}
+
+// IsNested reports whether any node has nested categories below it.
+func (n *CategoryNode) IsNested() bool {
+	for _, child := range n.Children {
+		if len(child.Children) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCategoryCounts(t *testing.T) {
+	file := &MarkdownFile{Stories: []UserStory{
+		{ID: "1", Category: "Auth"},
+		{ID: "2", Category: "Bug"},
+		{ID: "3", Category: "Auth"},
+		{ID: "4"},
+	}}
+	want := []CategoryCount{{Name: "Auth", Stories: 2}, {Name: "Bug", Stories: 1}, {Name: "Uncategorized", Stories: 1}}
+	if got := file.CategoryCounts(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("CategoryCounts(nil) = %+v, want %+v", got, want)
+	}
+
+	taxonomy := &Taxonomy{Categories: []CategoryDefinition{{Name: "auth"}, {Name: "Billing"}}, Fallback: DefaultFallbackCategory}
+	want = []CategoryCount{
+		{Name: "Auth", Stories: 2, Declared: true},
+		{Name: "Bug", Stories: 1},
+		{Name: "Uncategorized", Stories: 1, Declared: true},
+		{Name: "Billing", Declared: true},
+	}
+	if got := file.CategoryCounts(taxonomy); !reflect.DeepEqual(got, want) {
+		t.Errorf("CategoryCounts(taxonomy) = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecategorizeStories(t *testing.T) {
+	file := &MarkdownFile{
+		Metadata: map[string]interface{}{
+			"categories": []interface{}{
+				"Auth",
+				map[string]interface{}{"name": "Login", "description": "Signing in"},
+				"Billing",
+			},
+			"category_fallback": "Misc",
+		},
+		Stories: []UserStory{
+			{ID: "1", Category: "Auth"},
+			{ID: "2", Category: "login"},
+			{ID: "3", Category: "Billing"},
+			{ID: "4", Category: "Misc"},
+		},
+	}
+	if changed := file.RecategorizeStories([]string{"Login", "Misc"}, "Auth"); changed != 2 {
+		t.Errorf("RecategorizeStories() changed %d stories, want 2", changed)
+	}
+	for _, story := range file.Stories {
+		if story.ID != "3" && story.Category != "Auth" {
+			t.Errorf("story %s has category %q, want Auth", story.ID, story.Category)
+		}
+	}
+
+	// Login is folded into the existing Auth entry, Billing is renamed in place.
+	changed, err := file.ReplaceTaxonomyCategories([]string{"Login", "Misc"}, "Auth")
+	if err != nil || !changed {
+		t.Fatalf("ReplaceTaxonomyCategories() = %v, %v", changed, err)
+	}
+	file.ReplaceTaxonomyCategories([]string{"billing"}, "Payments")
+	taxonomy, err := ParseTaxonomy(file.Metadata)
+	if err != nil {
+		t.Fatalf("ParseTaxonomy() error = %v", err)
+	}
+	if !reflect.DeepEqual(taxonomy.Names(), []string{"Auth", "Payments"}) || taxonomy.Fallback != "Auth" {
+		t.Errorf("taxonomy after merging = %+v", taxonomy)
+	}
+}
+
+func TestRecategorizeNestedStories(t *testing.T) {
+	file := &MarkdownFile{
+		Metadata: map[string]interface{}{
+			"categories": []interface{}{"Auth", "Auth/Password Reset", "Authorization", "Identity/Password Reset"},
+		},
+		Stories: []UserStory{
+			{ID: "1", Category: "Auth"},
+			{ID: "2", Category: "Auth/Password Reset"},
+			{ID: "3", Category: "auth/Login/SSO"},
+			{ID: "4", Category: "Authorization"},
+		},
+	}
+	if changed := file.RecategorizeStories([]string{"Auth"}, "Identity"); changed != 3 {
+		t.Errorf("RecategorizeStories() changed %d stories, want 3", changed)
+	}
+	var categories []string
+	for _, story := range file.Stories {
+		categories = append(categories, story.Category)
+	}
+	want := []string{"Identity", "Identity/Password Reset", "Identity/Login/SSO", "Authorization"}
+	if !reflect.DeepEqual(categories, want) {
+		t.Errorf("categories after rename = %v, want %v", categories, want)
+	}
+
+	// Auth/Password Reset folds into the Identity/Password Reset entry that already exists.
+	if changed, err := file.ReplaceTaxonomyCategories([]string{"Auth"}, "Identity"); err != nil || !changed {
+		t.Fatalf("ReplaceTaxonomyCategories() = %v, %v", changed, err)
+	}
+	taxonomy, err := ParseTaxonomy(file.Metadata)
+	if err != nil {
+		t.Fatalf("ParseTaxonomy() error = %v", err)
+	}
+	if want := []string{"Identity", "Authorization", "Identity/Password Reset"}; !reflect.DeepEqual(taxonomy.Names(), want) {
+		t.Errorf("taxonomy after rename = %v, want %v", taxonomy.Names(), want)
+	}
+
+	// Merging an epic into a category nested below it only moves the epic's own stories.
+	file.Stories = []UserStory{{ID: "1", Category: "Auth"}, {ID: "2", Category: "Auth/Login"}, {ID: "3", Category: "Auth/SSO"}}
+	if changed := file.RecategorizeStories([]string{"Auth"}, "Auth/Login"); changed != 1 || file.Stories[2].Category != "Auth/SSO" {
+		t.Errorf("RecategorizeStories() into a nested category changed %d stories: %+v", changed, file.Stories)
+	}
+}
+
+func TestCategoryWithin(t *testing.T) {
+	tests := []struct {
+		category, ancestor string
+		want               bool
+	}{
+		{"Auth", "auth", true},
+		{"Auth/Password Reset", "Auth", true},
+		{"Auth / Password Reset", "auth/password reset", true},
+		{"Authorization", "Auth", false},
+		{"Auth", "Auth/Password Reset", false},
+	}
+	for _, tt := range tests {
+		if got := CategoryWithin(tt.category, tt.ancestor); got != tt.want {
+			t.Errorf("CategoryWithin(%q, %q) = %v, want %v", tt.category, tt.ancestor, got, tt.want)
+		}
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	tree := BuildCategoryTree([]UserStory{
+		{ID: "1", Category: "Auth/Password Reset"},
+		{ID: "2", Category: "Bug"},
+		{ID: "3", Category: "Auth"},
+		{ID: "4", Category: "Auth/Login"},
+		{ID: "5", Category: "Auth/Password Reset"},
+	})
+	if !tree.IsNested() {
+		t.Error("IsNested() = false, want true")
+	}
+	tree.SortChildren(false)
+
+	var got []string
+	var walk func(node *CategoryNode)
+	walk = func(node *CategoryNode) {
+		for _, child := range node.Children {
+			got = append(got, fmt.Sprintf("%s=%d", child.Path, child.Count()))
+			walk(child)
+		}
+	}
+	walk(tree)
+	want := []string{"Auth=4", "Auth/Login=1", "Auth/Password Reset=2", "Bug=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tree = %v, want %v", got, want)
+	}
+
+	if BuildCategoryTree([]UserStory{{Category: "Auth"}, {}}).IsNested() {
+		t.Error("IsNested() = true for flat categories")
+	}
+}
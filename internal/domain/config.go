@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the muserstory configuration file.
+type Config struct {
+	LLM   LLMConfig   `yaml:"llm"`
+	Cache CacheConfig `yaml:"cache"`
+	// Pricing adds to or overrides the default price table, keyed by model name.
+	Pricing PriceTable `yaml:"pricing"`
+	// UsageLog is the file usage is appended to; it defaults to the user config directory.
+	UsageLog string `yaml:"usage_log"`
+	// PromptsDir holds <prompt name>.tmpl files that replace the built-in prompts.
+	PromptsDir string `yaml:"prompts_dir"`
+	// Reviewer is recorded on stories accepted or declined with review; it defaults to
+	// the login name.
+	Reviewer string `yaml:"reviewer"`
+}
+
+// CacheConfig controls the on-disk cache of LLM responses. Zero values use the defaults.
+type CacheConfig struct {
+	Disabled bool `yaml:"disabled"`
+	// Dir defaults to <user cache dir>/muserstory/llm.
+	Dir        string        `yaml:"dir"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+type LLMConfig struct {
+	// Provider names the LLM provider to use, e.g. "openai", "azure", "anthropic" or "ollama".
+	Provider  string                       `yaml:"provider"`
+	Providers map[string]LLMProviderConfig `yaml:"providers"`
+}
+
+// LLMProviderConfig holds the connection settings of a single provider. Empty values
+// fall back to the provider's environment variables and defaults.
+type LLMProviderConfig struct {
+	APIKey  string `yaml:"api_key"`
+	BaseURL string `yaml:"base_url"`
+	// APIVersion is only used by Azure OpenAI.
+	APIVersion string `yaml:"api_version"`
+	// Fixtures is only used by the offline provider: a JSON file mapping prompt hashes to responses.
+	Fixtures string `yaml:"fixtures"`
+	// Timeout bounds a single request unless the model settings set their own.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries is how often rate-limited and failed requests are repeated; nil uses the default.
+	MaxRetries *int `yaml:"max_retries"`
+	// RequestsPerMinute spreads requests to stay within the budget; zero means no limit.
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// Models overrides the settings per model type (Simple, Advanced, ReasoningSimple,
+	// ReasoningAdvanced). A plain string only sets the model name.
+	Models map[string]ModelSettings `yaml:"models"`
+}
+
+// ModelSettings describe the concrete model used for a ModelType and how it is called.
+// Zero values leave the provider defaults in place.
+type ModelSettings struct {
+	Model       string        `yaml:"model"`
+	Temperature *float64      `yaml:"temperature"`
+	MaxTokens   int           `yaml:"max_tokens"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// UnmarshalYAML accepts either a mapping or a bare model name.
+func (m *ModelSettings) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		m.Model = node.Value
+		return nil
+	}
+	type plain ModelSettings
+	return node.Decode((*plain)(m))
+}
+
+// ModelSettingsFor returns the settings for modelType: defaultModel overridden by the
+// configured settings, whose keys are matched case-insensitively. The provider timeout
+// applies when the model has none.
+func (c LLMProviderConfig) ModelSettingsFor(modelType ModelType, defaultModel string) ModelSettings {
+	settings := ModelSettings{Model: defaultModel}
+	for name, configured := range c.Models {
+		if !strings.EqualFold(name, string(modelType)) {
+			continue
+		}
+		if configured.Model != "" {
+			settings.Model = configured.Model
+		}
+		settings.Temperature = configured.Temperature
+		settings.MaxTokens = configured.MaxTokens
+		settings.Timeout = configured.Timeout
+	}
+	if settings.Timeout == 0 {
+		settings.Timeout = c.Timeout
+	}
+	return settings
+}
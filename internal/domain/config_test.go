@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLLMProviderConfigModelSettingsFor(t *testing.T) {
+	content := `
+timeout: 45s
+models:
+  simple: gpt-4.1-mini
+  Advanced:
+    model: gpt-4.1
+    temperature: 0.2
+    max_tokens: 800
+    timeout: 2m
+`
+	var cfg LLMProviderConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+
+	simple := cfg.ModelSettingsFor(ModelTypeSimple, "gpt-4o-mini")
+	if simple.Model != "gpt-4.1-mini" || simple.Temperature != nil || simple.Timeout != 45*time.Second {
+		t.Errorf("ModelSettingsFor(Simple) = %+v", simple)
+	}
+
+	advanced := cfg.ModelSettingsFor(ModelTypeAdvanced, "gpt-4o")
+	if advanced.Model != "gpt-4.1" || advanced.Temperature == nil || *advanced.Temperature != 0.2 ||
+		advanced.MaxTokens != 800 || advanced.Timeout != 2*time.Minute {
+		t.Errorf("ModelSettingsFor(Advanced) = %+v", advanced)
+	}
+
+	reasoning := cfg.ModelSettingsFor(ModelTypeReasoningAdvanced, "o1")
+	if reasoning.Model != "o1" {
+		t.Errorf("ModelSettingsFor(ReasoningAdvanced) = %+v, want default model", reasoning)
+	}
+}
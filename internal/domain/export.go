@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatYAML ExportFormat = "yaml"
+)
+
+// CSVColumns is the column order used for CSV exports. Spreadsheets depend on it,
+// so new columns must only ever be appended.
+var CSVColumns = []string{"id", "category", "status", "description", "priority", "estimate"}
+
+type exportDocument struct {
+	Metadata map[string]interface{} `json:"metadata" yaml:"metadata"`
+	Summary  string                 `json:"summary" yaml:"summary"`
+	Stories  []UserStory            `json:"stories" yaml:"stories"`
+}
+
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "json":
+		return ExportFormatJSON, nil
+	case "csv":
+		return ExportFormatCSV, nil
+	case "yaml", "yml":
+		return ExportFormatYAML, nil
+	}
+	return "", fmt.Errorf("unsupported export format '%s', expected json, csv or yaml", name)
+}
+
+// Export writes the file's metadata, summary and stories to w in the given format.
+// CSV output contains one row per story and no metadata or summary.
+func (m *MarkdownFile) Export(w io.Writer, format ExportFormat) error {
+	doc := exportDocument{
+		Metadata: m.Metadata,
+		Summary:  m.Summary,
+		Stories:  m.Stories,
+	}
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string]interface{})
+	}
+	if doc.Stories == nil {
+		doc.Stories = make([]UserStory, 0)
+	}
+
+	switch format {
+	case ExportFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("error encoding JSON export: %w", err)
+		}
+		return nil
+	case ExportFormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("error encoding YAML export: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("error finishing YAML export: %w", err)
+		}
+		return nil
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(CSVColumns); err != nil {
+			return fmt.Errorf("error writing CSV header: %w", err)
+		}
+		for _, story := range doc.Stories {
+			if err := writer.Write(storyCSVRecord(story)); err != nil {
+				return fmt.Errorf("error writing CSV row for story %s: %w", story.ID, err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("error writing CSV export: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported export format '%s'", format)
+}
+
+// ExportToFile writes the export to filePath, replacing any existing file.
+func (m *MarkdownFile) ExportToFile(filePath string, format ExportFormat) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening/creating file %s for writing: %w", filePath, err)
+	}
+	defer file.Close()
+	return m.Export(file, format)
+}
+
+// storyCSVRecord returns the values of story in CSVColumns order. Stories without an
+// estimate leave the column empty.
+func storyCSVRecord(story UserStory) []string {
+	estimate := ""
+	if story.Estimate > 0 {
+		estimate = FormatEstimate(story.Estimate)
+	}
+	return []string{story.ID, story.Category, string(story.Status), story.Description, string(story.Priority), estimate}
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarkdownFileExport(t *testing.T) {
+	file := &MarkdownFile{
+		Metadata: map[string]interface{}{"project_name": "Demo"},
+		Summary:  "A demo project",
+		Stories: []UserStory{
+			{ID: "1", Description: "As a user, I want to log in, quickly", Category: "Auth", Status: StatusDone, Priority: PriorityMust, Estimate: 2.5},
+			{ID: "2", Description: "As a user, I want dark mode", Category: "UI"},
+		},
+	}
+
+	var csvOut bytes.Buffer
+	if err := file.Export(&csvOut, ExportFormatCSV); err != nil {
+		t.Fatalf("Export(csv) error = %v", err)
+	}
+	wantCSV := "id,category,status,description,priority,estimate\n" +
+		"1,Auth,Done,\"As a user, I want to log in, quickly\",Must,2.5\n" +
+		"2,UI,,\"As a user, I want dark mode\",,\n"
+	if csvOut.String() != wantCSV {
+		t.Errorf("Export(csv) =\n%s\nwant\n%s", csvOut.String(), wantCSV)
+	}
+
+	var jsonOut bytes.Buffer
+	if err := file.Export(&jsonOut, ExportFormatJSON); err != nil {
+		t.Fatalf("Export(json) error = %v", err)
+	}
+	var doc exportDocument
+	if err := json.Unmarshal(jsonOut.Bytes(), &doc); err != nil {
+		t.Fatalf("Export(json) produced invalid JSON: %v", err)
+	}
+	if doc.Summary != file.Summary || len(doc.Stories) != 2 || doc.Metadata["project_name"] != "Demo" {
+		t.Errorf("Export(json) = %+v", doc)
+	}
+
+	if _, err := ParseExportFormat("xml"); err == nil {
+		t.Errorf("ParseExportFormat(xml) should fail")
+	}
+}
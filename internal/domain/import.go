@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatJSON ImportFormat = "json"
+	ImportFormatCSV  ImportFormat = "csv"
+	ImportFormatText ImportFormat = "text"
+)
+
+// Story fields that external columns can be mapped to.
+const (
+	ImportFieldID          = "id"
+	ImportFieldDescription = "description"
+	ImportFieldCategory    = "category"
+	ImportFieldStatus      = "status"
+)
+
+// defaultImportColumns lists, per story field, the column names recognised when no
+// explicit mapping is given. Matching ignores case and surrounding whitespace.
+var defaultImportColumns = map[string][]string{
+	ImportFieldID:          {"id", "uuid"},
+	ImportFieldDescription: {"description", "story", "user story", "title"},
+	ImportFieldCategory:    {"category"},
+	ImportFieldStatus:      {"status", "state"},
+}
+
+// ImportMapping maps story fields (id, description, category, status) to the
+// column or key name used in the imported data.
+type ImportMapping map[string]string
+
+// ParseImportMapping parses "field=column" pairs, e.g. "description=Summary".
+func ParseImportMapping(pairs []string) (ImportMapping, error) {
+	mapping := make(ImportMapping)
+	for _, pair := range pairs {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid mapping '%s', expected field=column", pair)
+		}
+		if _, known := defaultImportColumns[field]; !known {
+			return nil, fmt.Errorf("unknown story field '%s' in mapping, expected id, description, category or status", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// ParseImportFormat validates a format name. An empty name is inferred from the
+// file extension, falling back to plain text.
+func ParseImportFormat(name string, filePath string) (ImportFormat, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+		if name != "json" && name != "csv" {
+			name = "text"
+		}
+	}
+	switch name {
+	case "json":
+		return ImportFormatJSON, nil
+	case "csv":
+		return ImportFormatCSV, nil
+	case "text", "txt":
+		return ImportFormatText, nil
+	}
+	return "", fmt.Errorf("unsupported import format '%s', expected csv, json or text", name)
+}
+
+// ParseImportedStories reads stories from CSV, JSON or newline-separated text.
+// CSV needs a header row. JSON may be a list of objects or an object with a
+// "stories" list, such as the output of Export. Stories keep the ID found in
+// the data; IDs and categories may be empty and are left for the caller to fill.
+func ParseImportedStories(r io.Reader, format ImportFormat, mapping ImportMapping) ([]UserStory, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCSVStories(r, mapping)
+	case ImportFormatJSON:
+		return parseJSONStories(r, mapping)
+	case ImportFormatText:
+		return parseTextStories(r)
+	}
+	return nil, fmt.Errorf("unsupported import format '%s'", format)
+}
+
+func parseCSVStories(r io.Reader, mapping ImportMapping) ([]UserStory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columnIndex := make(map[string]int)
+	for field := range defaultImportColumns {
+		for i, header := range records[0] {
+			if mapping.matches(field, header) {
+				columnIndex[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := columnIndex[ImportFieldDescription]; !ok {
+		return nil, fmt.Errorf("CSV header has no description column, map one with description=<column>")
+	}
+
+	var stories []UserStory
+	for _, record := range records[1:] {
+		value := func(field string) string {
+			i, ok := columnIndex[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		stories = append(stories, UserStory{
+			ID:          value(ImportFieldID),
+			Description: value(ImportFieldDescription),
+			Category:    value(ImportFieldCategory),
+			Status:      Status(value(ImportFieldStatus)),
+		})
+	}
+	return stories, nil
+}
+
+func parseJSONStories(r io.Reader, mapping ImportMapping) ([]UserStory, error) {
+	var raw interface{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("error decoding JSON: %w", err)
+	}
+	if doc, ok := raw.(map[string]interface{}); ok {
+		raw = doc["stories"]
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON must be a list of stories or an object with a 'stories' list")
+	}
+
+	var stories []UserStory
+	for i, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("story %d is not a JSON object", i+1)
+		}
+		value := func(field string) string {
+			for key, v := range object {
+				if mapping.matches(field, key) && v != nil {
+					return strings.TrimSpace(fmt.Sprint(v))
+				}
+			}
+			return ""
+		}
+		stories = append(stories, UserStory{
+			ID:          value(ImportFieldID),
+			Description: value(ImportFieldDescription),
+			Category:    value(ImportFieldCategory),
+			Status:      Status(value(ImportFieldStatus)),
+		})
+	}
+	return stories, nil
+}
+
+// parseTextStories treats every non-empty line as a story description. Leading
+// list markers ("- ", "* ", "1. ") are removed.
+func parseTextStories(r io.Reader) ([]UserStory, error) {
+	var stories []UserStory
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*•"))
+		if number, rest, ok := strings.Cut(line, ". "); ok && number != "" && strings.IndexFunc(number, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+			line = strings.TrimSpace(rest)
+		}
+		if line == "" {
+			continue
+		}
+		stories = append(stories, UserStory{Description: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading text: %w", err)
+	}
+	return stories, nil
+}
+
+// matches reports whether the column name in the imported data belongs to field.
+// An explicit mapping replaces the default column names for that field.
+func (m ImportMapping) matches(field string, column string) bool {
+	column = strings.ToLower(strings.TrimSpace(column))
+	if mapped, ok := m[field]; ok {
+		return strings.ToLower(mapped) == column
+	}
+	for _, name := range defaultImportColumns[field] {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDescription lowercases a description and strips punctuation and
+// repeated whitespace so that trivially different descriptions compare equal.
+func NormalizeDescription(description string) string {
+	var normalized strings.Builder
+	lastWasSpace := true
+	for _, r := range strings.ToLower(description) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			normalized.WriteRune(r)
+			lastWasSpace = false
+		case !lastWasSpace:
+			normalized.WriteRune(' ')
+			lastWasSpace = true
+		}
+	}
+	return strings.TrimSpace(normalized.String())
+}
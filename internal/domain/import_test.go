@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportedStories(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  ImportFormat
+		mapping ImportMapping
+		input   string
+		want    []UserStory
+		wantErr bool
+	}{
+		{
+			name:   "csv with default columns",
+			format: ImportFormatCSV,
+			input:  "UUID,Story,Category,State\nabc,\"As a user, I want to log in\",Auth,done\n,As a user I want dark mode,,\n",
+			want: []UserStory{
+				{ID: "abc", Description: "As a user, I want to log in", Category: "Auth", Status: "done"},
+				{Description: "As a user I want dark mode"},
+			},
+		},
+		{
+			name:    "csv with mapping",
+			format:  ImportFormatCSV,
+			mapping: ImportMapping{ImportFieldDescription: "Summary", ImportFieldCategory: "Epic"},
+			input:   "Key,Summary,Epic\nPRJ-1,Export reports,Reporting\n",
+			want:    []UserStory{{Description: "Export reports", Category: "Reporting"}},
+		},
+		{
+			name:    "csv without description column",
+			format:  ImportFormatCSV,
+			input:   "Key,Summary\nPRJ-1,Export reports\n",
+			wantErr: true,
+		},
+		{
+			name:   "json export document",
+			format: ImportFormatJSON,
+			input:  `{"metadata": {}, "stories": [{"id": "abc", "description": "Log in", "category": "Auth", "status": "Done"}]}`,
+			want:   []UserStory{{ID: "abc", Description: "Log in", Category: "Auth", Status: StatusDone}},
+		},
+		{
+			name:   "json list",
+			format: ImportFormatJSON,
+			input:  `[{"title": "Log out", "points": 3}]`,
+			want:   []UserStory{{Description: "Log out"}},
+		},
+		{
+			name:   "text lines",
+			format: ImportFormatText,
+			input:  "- Log in\n\n1. Log out\n* Reset password\n",
+			want:   []UserStory{{Description: "Log in"}, {Description: "Log out"}, {Description: "Reset password"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImportedStories(strings.NewReader(tt.input), tt.format, tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImportedStories() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseImportedStories() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("story %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeDescription(t *testing.T) {
+	a := NormalizeDescription("As a user, I want to   log in!")
+	b := NormalizeDescription("as a user I want to log in")
+	if a != b {
+		t.Errorf("NormalizeDescription() = %q and %q, want equal", a, b)
+	}
+}
@@ -1,6 +1,11 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
 	"github.com/invopop/jsonschema"
 )
 
@@ -17,6 +22,9 @@ type LLMSimpleInput struct {
 	SystemMessage string
 	UserMessage   string
 	ModelType     ModelType
+	// Name identifies the kind of request, as SchemaName does for structured ones. It is
+	// not sent to the model.
+	Name string
 }
 
 type LLMAdvancedInput struct {
@@ -28,6 +36,20 @@ type LLMAdvancedInput struct {
 	Schema            interface{}
 }
 
+// LLMResponse is the answer to an LLM request together with what it cost.
+type LLMResponse struct {
+	Content string
+	Usage   LLMUsage
+}
+
+// LLMUsage is the token usage of a single request. Responses that were not produced by a
+// model, such as cached or offline answers, have zero tokens.
+type LLMUsage struct {
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
 func GenerateSchema[T any]() interface{} {
 	reflector := jsonschema.Reflector{
 		AllowAdditionalProperties: false,
@@ -37,3 +59,80 @@ func GenerateSchema[T any]() interface{} {
 	schema := reflector.Reflect(v)
 	return schema
 }
+
+// ConstrainEnum restricts every property called name in a schema made by GenerateSchema
+// to values, and returns the schema.
+func ConstrainEnum(schema interface{}, name string, values []string) interface{} {
+	enum := make([]any, 0, len(values))
+	for _, value := range values {
+		enum = append(enum, value)
+	}
+	walkSchema(schema, func(property string, s *jsonschema.Schema) {
+		if property == name {
+			s.Enum = enum
+		}
+	})
+	return schema
+}
+
+// ConstrainItems makes every array property called name in a schema made by
+// GenerateSchema hold exactly count items, and returns the schema.
+func ConstrainItems(schema interface{}, name string, count int) interface{} {
+	items := uint64(count)
+	walkSchema(schema, func(property string, s *jsonschema.Schema) {
+		if property == name && s.Type == "array" {
+			s.MinItems, s.MaxItems = &items, &items
+		}
+	})
+	return schema
+}
+
+// walkSchema calls visit for every property of a schema made by GenerateSchema, including
+// the properties of nested objects and array items.
+func walkSchema(schema interface{}, visit func(property string, s *jsonschema.Schema)) {
+	root, ok := schema.(*jsonschema.Schema)
+	if !ok {
+		return
+	}
+	var walk func(s *jsonschema.Schema)
+	walk = func(s *jsonschema.Schema) {
+		if s == nil {
+			return
+		}
+		if s.Properties != nil {
+			for pair := s.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				visit(pair.Key, pair.Value)
+				walk(pair.Value)
+			}
+		}
+		walk(s.Items)
+	}
+	walk(root)
+}
+
+// PromptHash identifies an LLM request by its model type, messages and schema name.
+// Simple requests have no schema name.
+func PromptHash(modelType ModelType, systemMessage string, userMessage string, schemaName string) string {
+	hash := sha256.New()
+	for _, part := range []string{string(modelType), systemMessage, userMessage, schemaName} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CacheKey identifies a cached response to the request identified by promptHash. It also
+// covers the serialized schema, so requests whose schemas differ only in, say, the taxonomy
+// enum do not share a response, and the provider and concrete model that answer it.
+func CacheKey(provider string, model string, promptHash string, schema interface{}) string {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		schemaJSON = []byte(fmt.Sprintf("%#v", schema))
+	}
+	hash := sha256.New()
+	for _, part := range []string{provider, model, promptHash, string(schemaJSON)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of LLM failures. LLM services return errors that match one of these with errors.Is
+// when the cause is known.
+var (
+	ErrLLMRateLimited   = errors.New("llm rate limit exceeded")
+	ErrLLMAuthFailed    = errors.New("llm authentication failed")
+	ErrLLMEmptyResponse = errors.New("llm returned an empty response")
+	// ErrLLMUnavailable covers server errors and network failures that may pass on retry.
+	ErrLLMUnavailable = errors.New("llm service unavailable")
+)
+
+// LLMError is a failed LLM request of a known kind.
+type LLMError struct {
+	Kind       error
+	StatusCode int
+	// RetryAfter is how long the server asked to wait before the next request, if it said so.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *LLMError) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *LLMError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *LLMError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryableLLMError reports whether a request that failed with err may succeed when repeated.
+func IsRetryableLLMError(err error) bool {
+	return errors.Is(err, ErrLLMRateLimited) || errors.Is(err, ErrLLMUnavailable)
+}
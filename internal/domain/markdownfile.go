@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
@@ -56,7 +57,10 @@ func ParseMarkdownFileContent(content string) (*MarkdownFile, error) {
 		}
 
 		if isReadingSummary {
-			if strings.HasPrefix(trimmedLine, "- ") || strings.HasPrefix(trimmedLine, "**") {
+			// Only a top-level category heading, as written after the summary, ends it; deeper
+			// headings belong to the summary.
+			level, _, isHeading := parseCategoryHeading(trimmedLine)
+			if strings.HasPrefix(trimmedLine, "- ") || strings.HasPrefix(trimmedLine, "**") || isHeading && level == 0 {
 				isReadingSummary = false
 				storyContentLines = append(storyContentLines, line)
 			} else {
@@ -74,53 +78,59 @@ func ParseMarkdownFileContent(content string) (*MarkdownFile, error) {
 		return nil, fmt.Errorf("error reading content: %w", err)
 	}
 
-	lineNumber := 0
+	// headingPath holds the nested category headings above the current line, so a story
+	// without a Category tag is filed under the heading it is written below.
+	var headingPath []string
 	for _, line := range storyContentLines {
-		lineNumber++
 		trimmedLine := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmedLine, "- ") {
-			content := strings.TrimPrefix(trimmedLine, "- ")
-			description := content
-			category := "Uncategorized"
-
-			// Parse [Category: ...] and [UUID: ...] tags
-			catStartIndex := strings.LastIndex(content, "[Category: ")
-			catEndIndex := -1
-			uuidStartIndex := strings.LastIndex(content, "[UUID: ")
-			uuidEndIndex := -1
-
-			if catStartIndex != -1 {
-				catEndIndex = strings.Index(content[catStartIndex:], "]")
-				if catEndIndex != -1 {
-					catEndIndex += catStartIndex
-				}
-			}
-			if uuidStartIndex != -1 {
-				uuidEndIndex = strings.Index(content[uuidStartIndex:], "]")
-				if uuidEndIndex != -1 {
-					uuidEndIndex += uuidStartIndex
-				}
+		if level, title, ok := parseCategoryHeading(trimmedLine); ok {
+			if level > len(headingPath) {
+				level = len(headingPath)
 			}
-
-			// Extract description, category, uuid
-			if catStartIndex != -1 && catEndIndex != -1 {
-				description = strings.TrimSpace(content[:catStartIndex])
-				category = strings.TrimSpace(content[catStartIndex+len("[Category: ") : catEndIndex])
+			headingPath = append(headingPath[:level], title)
+			continue
+		}
+		content, isBullet := strings.CutPrefix(trimmedLine, "- ")
+		description, tags := parseStoryTags(content)
+		// An indented line below a story is one of its acceptance criteria, unless it is a
+		// bullet with story tags: indented story bullets are read as stories.
+		isStory := isBullet && (len(tags) > 0 || line[0] != ' ' && line[0] != '\t')
+		if trimmedLine != "" && !isStory && (line[0] == ' ' || line[0] == '\t') && len(stories) > 0 {
+			last := &stories[len(stories)-1]
+			last.AcceptanceCriteria = addCriterionLine(last.AcceptanceCriteria, trimmedLine)
+			continue
+		}
+		if isBullet {
+			category := NormalizeCategoryPath(tags[tagCategory])
+			if category == "" {
+				category = NormalizeCategoryPath(strings.Join(headingPath, CategoryPathSeparator))
 			}
-
-			storyUUID := ""
-			if uuidStartIndex != -1 && uuidEndIndex != -1 {
-				storyUUID = strings.TrimSpace(content[uuidStartIndex+len("[UUID: ") : uuidEndIndex])
+			if category == "" {
+				category = "Uncategorized"
 			}
 
+			storyUUID := tags[tagUUID]
 			if storyUUID == "" {
 				storyUUID = uuid.NewString()
 			}
 
+			// A malformed priority or estimate is dropped rather than failing the whole file.
+			priority, _ := ParsePriority(tags[tagPriority])
+			estimate, _ := ParseEstimate(tags[tagEstimate])
+			var decidedAt *time.Time
+			if at, err := time.Parse(time.RFC3339, tags[tagDecidedAt]); err == nil {
+				decidedAt = &at
+			}
+
 			stories = append(stories, UserStory{
 				ID:          storyUUID,
 				Description: description,
 				Category:    category,
+				Status:      Status(tags[tagStatus]),
+				Priority:    priority,
+				Estimate:    estimate,
+				DecidedBy:   tags[tagDecidedBy],
+				DecidedAt:   decidedAt,
 			})
 		}
 	}
@@ -132,14 +142,30 @@ func ParseMarkdownFileContent(content string) (*MarkdownFile, error) {
 	}, nil
 }
 
+// WriteToFile writes the file to a temporary file next to filePath and then renames it,
+// so an interrupted write never leaves a truncated story file behind.
 func (m *MarkdownFile) WriteToFile(filePath string) error {
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	tmpPath := filePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		return fmt.Errorf("error opening/creating file %s for writing: %w", filePath, err)
+		return fmt.Errorf("error opening/creating file %s for writing: %w", tmpPath, err)
 	}
+	defer os.Remove(tmpPath)
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
+	if err := m.write(bufio.NewWriter(file)); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing file %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("error replacing file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+func (m *MarkdownFile) write(writer *bufio.Writer) error {
 
 	if len(m.Metadata) > 0 {
 		if _, err := writer.WriteString("---\n"); err != nil {
@@ -166,6 +192,14 @@ func (m *MarkdownFile) WriteToFile(filePath string) error {
 		}
 	}
 
+	tree := BuildCategoryTree(m.Stories)
+	if tree.IsNested() {
+		if err := writeCategoryTree(writer, tree); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+
 	if len(m.Stories) > 0 {
 		storiesByCategory := make(map[string][]UserStory)
 		var categoryOrder []string
@@ -186,8 +220,7 @@ func (m *MarkdownFile) WriteToFile(filePath string) error {
 				return fmt.Errorf("error writing category header: %w", err)
 			}
 			for _, story := range storiesByCategory[category] {
-				line := fmt.Sprintf("- %s [Category: %s] [UUID: %s]\n", story.Description, story.Category, story.ID)
-				if _, err := writer.WriteString(line); err != nil {
+				if _, err := writer.WriteString(formatStoryLine(story)); err != nil {
 					return fmt.Errorf("error writing story: %w", err)
 				}
 			}
@@ -199,3 +232,195 @@ func (m *MarkdownFile) WriteToFile(filePath string) error {
 
 	return writer.Flush()
 }
+
+// categoryHeadingLevel is the markdown heading level of top-level categories in a file
+// with nested categories; "# Summary" is the only first-level heading.
+const categoryHeadingLevel = 2
+
+// maxHeadingLevel is the deepest heading markdown has. Deeper categories share it and
+// rely on their Category tag when the file is read back.
+const maxHeadingLevel = 6
+
+// parseCategoryHeading recognizes a "## Name" category heading and returns its depth in
+// the category tree, zero for top-level categories.
+func parseCategoryHeading(line string) (int, string, bool) {
+	hashes := len(line) - len(strings.TrimLeft(line, "#"))
+	if hashes < categoryHeadingLevel || hashes > maxHeadingLevel || !strings.HasPrefix(line[hashes:], " ") {
+		return 0, "", false
+	}
+	title := strings.TrimSpace(line[hashes:])
+	if title == "" {
+		return 0, "", false
+	}
+	return hashes - categoryHeadingLevel, title, true
+}
+
+// writeCategoryTree writes stories below one markdown heading per category level, e.g.
+// "## Auth" followed by "### Password Reset".
+func writeCategoryTree(writer *bufio.Writer, node *CategoryNode) error {
+	for _, child := range node.Children {
+		level := categoryHeadingLevel + strings.Count(child.Path, CategoryPathSeparator)
+		if level > maxHeadingLevel {
+			level = maxHeadingLevel
+		}
+		if _, err := writer.WriteString(fmt.Sprintf("%s %s\n", strings.Repeat("#", level), child.Name)); err != nil {
+			return fmt.Errorf("error writing category header: %w", err)
+		}
+		for _, story := range child.Stories {
+			if _, err := writer.WriteString(formatStoryLine(story)); err != nil {
+				return fmt.Errorf("error writing story: %w", err)
+			}
+		}
+		if _, err := writer.WriteString("\n"); err != nil {
+			return fmt.Errorf("error writing newline: %w", err)
+		}
+		if err := writeCategoryTree(writer, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	tagCategory  = "Category"
+	tagStatus    = "Status"
+	tagPriority  = "Priority"
+	tagEstimate  = "Estimate"
+	tagDecidedBy = "Decided By"
+	tagDecidedAt = "Decided At"
+	tagUUID      = "UUID"
+)
+
+// knownStoryTags lists the trailing "[Key: value]" tags understood on a story line.
+var knownStoryTags = map[string]bool{
+	tagCategory:  true,
+	tagStatus:    true,
+	tagPriority:  true,
+	tagEstimate:  true,
+	tagDecidedBy: true,
+	tagDecidedAt: true,
+	tagUUID:      true,
+}
+
+// parseStoryTags splits a story line into its description and the trailing
+// "[Key: value]" tags. Unknown bracketed text is kept as part of the description.
+func parseStoryTags(content string) (string, map[string]string) {
+	tags := make(map[string]string)
+	rest := strings.TrimSpace(content)
+	for strings.HasSuffix(rest, "]") {
+		start := strings.LastIndex(rest, "[")
+		if start == -1 {
+			break
+		}
+		key, value, ok := strings.Cut(rest[start+1:len(rest)-1], ": ")
+		if !ok || !knownStoryTags[key] {
+			break
+		}
+		tags[key] = strings.TrimSpace(value)
+		rest = strings.TrimSpace(rest[:start])
+	}
+	return rest, tags
+}
+
+// scenarioKeywords start the steps of a Given/When/Then acceptance criterion.
+var scenarioKeywords = []string{"Given", "When", "Then", "And", "But"}
+
+// scenarioKeyword returns the Given/When/Then keyword a criterion line starts with.
+func scenarioKeyword(line string) string {
+	for _, keyword := range scenarioKeywords {
+		if len(line) > len(keyword) && strings.EqualFold(line[:len(keyword)], keyword) && line[len(keyword)] == ' ' {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// addCriterionLine adds an indented line below a story to its acceptance criteria. A
+// sub-bullet or a Given line starts a new criterion; When, Then, And and But lines
+// continue the scenario started before them.
+func addCriterionLine(criteria []string, line string) []string {
+	if text, ok := strings.CutPrefix(line, "- "); ok {
+		return append(criteria, strings.TrimSpace(text))
+	}
+	if text, ok := strings.CutPrefix(line, "* "); ok {
+		return append(criteria, strings.TrimSpace(text))
+	}
+	keyword := scenarioKeyword(line)
+	if keyword != "" && keyword != "Given" && len(criteria) > 0 && scenarioKeyword(criteria[len(criteria)-1]) != "" {
+		criteria[len(criteria)-1] += "\n" + line
+		return criteria
+	}
+	return append(criteria, line)
+}
+
+// formatCriteria writes single-line criteria as sub-bullets and scenarios as indented
+// Given/When/Then steps.
+func formatCriteria(criteria []string) string {
+	var lines strings.Builder
+	for _, criterion := range criteria {
+		steps := strings.Split(strings.TrimSpace(criterion), "\n")
+		if len(steps) > 1 && scenarioKeyword(steps[0]) != "" {
+			for _, step := range steps {
+				lines.WriteString("  " + strings.TrimSpace(step) + "\n")
+			}
+			continue
+		}
+		lines.WriteString("  - " + strings.Join(strings.Fields(criterion), " ") + "\n")
+	}
+	return lines.String()
+}
+
+func formatStoryLine(story UserStory) string {
+	var line strings.Builder
+	line.WriteString(fmt.Sprintf("- %s [Category: %s]", story.Description, story.Category))
+	if story.Status != "" {
+		line.WriteString(fmt.Sprintf(" [Status: %s]", story.Status))
+	}
+	if story.Priority != "" {
+		line.WriteString(fmt.Sprintf(" [Priority: %s]", story.Priority))
+	}
+	if story.Estimate > 0 {
+		line.WriteString(fmt.Sprintf(" [Estimate: %s]", FormatEstimate(story.Estimate)))
+	}
+	if story.DecidedBy != "" {
+		line.WriteString(fmt.Sprintf(" [Decided By: %s]", story.DecidedBy))
+	}
+	if story.DecidedAt != nil {
+		line.WriteString(fmt.Sprintf(" [Decided At: %s]", story.DecidedAt.UTC().Format(time.RFC3339)))
+	}
+	line.WriteString(fmt.Sprintf(" [UUID: %s]\n", story.ID))
+	line.WriteString(formatCriteria(story.AcceptanceCriteria))
+	return line.String()
+}
+
+// FindStory returns the index of the story whose UUID equals id or, like a git short
+// hash, starts with it. An exact match wins; an ambiguous prefix is an error.
+func (m *MarkdownFile) FindStory(id string) (int, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return -1, fmt.Errorf("story UUID must not be empty")
+	}
+
+	var matches []int
+	for i, story := range m.Stories {
+		storyID := strings.ToLower(story.ID)
+		if storyID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(storyID, id) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no story found with UUID '%s'", id)
+	case 1:
+		return matches[0], nil
+	}
+	ids := make([]string, len(matches))
+	for i, index := range matches {
+		ids[i] = m.Stories[index].ID
+	}
+	return -1, fmt.Errorf("UUID prefix '%s' is ambiguous, it matches: %s", id, strings.Join(ids, ", "))
+}
@@ -1,7 +1,12 @@
 package domain
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseMarkdownFileContent(t *testing.T) {
@@ -103,3 +108,227 @@ Super simple app
 		})
 	}
 }
+
+func TestMarkdownFileStatusRoundTrip(t *testing.T) {
+	content := `
+**Auth**
+- As a user, I want to log in [Category: Auth] [Status: In Progress] [Decided By: alex] [Decided At: 2026-03-01T09:30:00Z] [UUID: 11111111-1111-1111-1111-111111111111]
+- As a user, I want to log out [Category: Auth] [Priority: should] [Estimate: 0.5] [UUID: 22222222-2222-2222-2222-222222222222]
+`
+	parsed, err := ParseMarkdownFileContent(content)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() error = %v", err)
+	}
+	if len(parsed.Stories) != 2 {
+		t.Fatalf("ParseMarkdownFileContent() number of stories = %v, want 2", len(parsed.Stories))
+	}
+	if parsed.Stories[1].Priority != PriorityShould || parsed.Stories[1].Estimate != 0.5 {
+		t.Errorf("second story Priority, Estimate = %q, %v, want Should, 0.5", parsed.Stories[1].Priority, parsed.Stories[1].Estimate)
+	}
+	if first := parsed.Stories[0]; first.DecidedBy != "alex" || first.DecidedAt == nil || first.DecidedAt.Format(time.RFC3339) != "2026-03-01T09:30:00Z" {
+		t.Errorf("first story decision = %q at %v", first.DecidedBy, first.DecidedAt)
+	}
+	if parsed.Stories[0].Status != StatusInProgress {
+		t.Errorf("first story Status = %q, want %q", parsed.Stories[0].Status, StatusInProgress)
+	}
+	if parsed.Stories[0].Description != "As a user, I want to log in" {
+		t.Errorf("first story Description = %q", parsed.Stories[0].Description)
+	}
+	if parsed.Stories[1].Status != "" {
+		t.Errorf("second story Status = %q, want empty", parsed.Stories[1].Status)
+	}
+
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := parsed.WriteToFile(path); err != nil {
+		t.Fatalf("WriteToFile() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading written file: %v", err)
+	}
+	reparsed, err := ParseMarkdownFileContent(string(written))
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() on written file error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Stories, reparsed.Stories) {
+		t.Errorf("stories changed after round trip:\n got %+v\nwant %+v", reparsed.Stories, parsed.Stories)
+	}
+}
+
+func TestMarkdownFileNestedCategories(t *testing.T) {
+	content := `# Summary
+A login system.
+
+## Auth
+- As a user, I want to log in [Category: Auth] [UUID: 11111111-1111-1111-1111-111111111111]
+
+### Password Reset
+- As a user, I want to reset my password by email
+- As a user, I want to pick a new password [Category: Auth / Password Reset] [UUID: 33333333-3333-3333-3333-333333333333]
+
+## Billing
+- As a customer, I want an invoice [UUID: 44444444-4444-4444-4444-444444444444]
+`
+	parsed, err := ParseMarkdownFileContent(content)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() error = %v", err)
+	}
+	if parsed.Summary != "A login system." {
+		t.Errorf("Summary = %q, want the text before the first heading", parsed.Summary)
+	}
+	var categories []string
+	for _, story := range parsed.Stories {
+		categories = append(categories, story.Category)
+	}
+	want := []string{"Auth", "Auth/Password Reset", "Auth/Password Reset", "Billing"}
+	if !reflect.DeepEqual(categories, want) {
+		t.Fatalf("categories = %v, want %v", categories, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := parsed.WriteToFile(path); err != nil {
+		t.Fatalf("WriteToFile() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading written file: %v", err)
+	}
+	if !strings.Contains(string(written), "## Auth\n") || !strings.Contains(string(written), "### Password Reset\n") {
+		t.Errorf("written file lacks nested headings:\n%s", written)
+	}
+	reparsed, err := ParseMarkdownFileContent(string(written))
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() on written file error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Stories, reparsed.Stories) {
+		t.Errorf("stories changed after round trip:\n got %+v\nwant %+v", reparsed.Stories, parsed.Stories)
+	}
+}
+
+func TestMarkdownFileSummaryWithHeadings(t *testing.T) {
+	content := `# Summary
+A login system.
+
+### Goals
+Fewer support tickets.
+
+## Auth
+### Password Reset
+- As a user, I want to reset my password [UUID: 11111111-1111-1111-1111-111111111111]
+`
+	parsed, err := ParseMarkdownFileContent(content)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() error = %v", err)
+	}
+	if want := "A login system.\n\n### Goals\nFewer support tickets."; parsed.Summary != want {
+		t.Errorf("Summary = %q, want %q", parsed.Summary, want)
+	}
+	if len(parsed.Stories) != 1 || parsed.Stories[0].Category != "Auth/Password Reset" {
+		t.Errorf("Stories = %+v, want one story in Auth/Password Reset", parsed.Stories)
+	}
+}
+
+func TestMarkdownFileAcceptanceCriteria(t *testing.T) {
+	content := `**Auth**
+- As a user, I want to reset my password [Category: Auth] [UUID: 11111111-1111-1111-1111-111111111111]
+  - The reset link expires after 24 hours
+  Given a registered user
+  When they request a password reset
+  Then an email with a reset link is sent
+  * Old passwords cannot be reused
+- As a user, I want to log out [Category: Auth] [UUID: 22222222-2222-2222-2222-222222222222]
+`
+	parsed, err := ParseMarkdownFileContent(content)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() error = %v", err)
+	}
+	if len(parsed.Stories) != 2 {
+		t.Fatalf("ParseMarkdownFileContent() number of stories = %v, want 2", len(parsed.Stories))
+	}
+	want := []string{
+		"The reset link expires after 24 hours",
+		"Given a registered user\nWhen they request a password reset\nThen an email with a reset link is sent",
+		"Old passwords cannot be reused",
+	}
+	if !reflect.DeepEqual(parsed.Stories[0].AcceptanceCriteria, want) {
+		t.Errorf("AcceptanceCriteria = %q, want %q", parsed.Stories[0].AcceptanceCriteria, want)
+	}
+	if parsed.Stories[1].AcceptanceCriteria != nil {
+		t.Errorf("second story AcceptanceCriteria = %q, want none", parsed.Stories[1].AcceptanceCriteria)
+	}
+
+	path := filepath.Join(t.TempDir(), "stories.md")
+	if err := parsed.WriteToFile(path); err != nil {
+		t.Fatalf("WriteToFile() error = %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading written file: %v", err)
+	}
+	if !strings.Contains(string(written), "  Given a registered user\n  When they request") {
+		t.Errorf("scenario not written as steps:\n%s", written)
+	}
+	reparsed, err := ParseMarkdownFileContent(string(written))
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() on written file error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Stories, reparsed.Stories) {
+		t.Errorf("stories changed after round trip:\n got %+v\nwant %+v", reparsed.Stories, parsed.Stories)
+	}
+}
+
+func TestMarkdownFileIndentedStories(t *testing.T) {
+	content := `**Auth**
+- As a user, I want to log in [Category: Auth] [UUID: 11111111-1111-1111-1111-111111111111]
+  - The session lasts a week
+  - As a user, I want to log out [Category: Auth] [UUID: 22222222-2222-2222-2222-222222222222]
+  - As an admin, I want to lock accounts [Category: Admin]
+`
+	parsed, err := ParseMarkdownFileContent(content)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFileContent() error = %v", err)
+	}
+	if len(parsed.Stories) != 3 {
+		t.Fatalf("ParseMarkdownFileContent() number of stories = %v, want 3: %+v", len(parsed.Stories), parsed.Stories)
+	}
+	if want := []string{"The session lasts a week"}; !reflect.DeepEqual(parsed.Stories[0].AcceptanceCriteria, want) {
+		t.Errorf("AcceptanceCriteria = %q, want %q", parsed.Stories[0].AcceptanceCriteria, want)
+	}
+	if parsed.Stories[1].ID != "22222222-2222-2222-2222-222222222222" || parsed.Stories[1].Description != "As a user, I want to log out" {
+		t.Errorf("indented tagged story = %+v, want the log out story", parsed.Stories[1])
+	}
+	if parsed.Stories[2].Category != "Admin" {
+		t.Errorf("indented story without UUID Category = %q, want Admin", parsed.Stories[2].Category)
+	}
+}
+
+func TestMarkdownFileFindStory(t *testing.T) {
+	file := &MarkdownFile{Stories: []UserStory{
+		{ID: "3f2a9c1e-0000-0000-0000-000000000001"},
+		{ID: "3f2b0000-0000-0000-0000-000000000002"},
+		{ID: "3f2"},
+	}}
+
+	tests := []struct {
+		id      string
+		want    int
+		wantErr bool
+	}{
+		{id: "3F2A", want: 0},
+		{id: "3f2b0000-0000-0000-0000-000000000002", want: 1},
+		{id: "3f2", want: 2},
+		{id: "3f", wantErr: true},
+		{id: "ffff", wantErr: true},
+		{id: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := file.FindStory(tt.id)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FindStory(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("FindStory(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
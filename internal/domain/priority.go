@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Priority is either a MoSCoW bucket or a positive rank, where 1 is the most important.
+type Priority string
+
+const (
+	PriorityMust   Priority = "Must"
+	PriorityShould Priority = "Should"
+	PriorityCould  Priority = "Could"
+	PriorityWont   Priority = "Won't"
+)
+
+// MoSCoWPriorities lists the MoSCoW priorities from most to least important.
+func MoSCoWPriorities() []Priority {
+	return []Priority{PriorityMust, PriorityShould, PriorityCould, PriorityWont}
+}
+
+// ParsePriority accepts a MoSCoW priority, loosely written as e.g. "must have", "M" or
+// "wont", or a positive number. An empty name clears the priority.
+func ParsePriority(name string) (Priority, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	if rank, err := strconv.Atoi(name); err == nil {
+		if rank < 1 {
+			return "", fmt.Errorf("numeric priority must be 1 or higher, got %d", rank)
+		}
+		return Priority(strconv.Itoa(rank)), nil
+	}
+
+	normalized := strings.TrimSuffix(strings.ToLower(name), " have")
+	normalized = strings.NewReplacer("'", "", "’", "").Replace(normalized)
+	for _, priority := range MoSCoWPriorities() {
+		key := strings.ToLower(strings.ReplaceAll(string(priority), "'", ""))
+		if normalized == key || normalized == key[:1] {
+			return priority, nil
+		}
+	}
+	return "", fmt.Errorf("unknown priority '%s', expected Must, Should, Could, Won't or a number", name)
+}
+
+// IsMoSCoW reports whether the priority is one of the MoSCoW priorities.
+func (p Priority) IsMoSCoW() bool {
+	for _, priority := range MoSCoWPriorities() {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank orders priorities, a lower rank being more important. MoSCoW priorities rank 1 to
+// 4 and numeric priorities rank as their number; a story without priority has no rank.
+func (p Priority) Rank() (int, bool) {
+	for i, priority := range MoSCoWPriorities() {
+		if p == priority {
+			return i + 1, true
+		}
+	}
+	rank, err := strconv.Atoi(string(p))
+	if err != nil {
+		return 0, false
+	}
+	return rank, true
+}
+
+// ParseEstimate reads a story-point estimate such as "3" or "0.5". An empty value clears
+// the estimate.
+func ParseEstimate(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	estimate, err := strconv.ParseFloat(value, 64)
+	if err != nil || estimate < 0 {
+		return 0, fmt.Errorf("estimate must be a non-negative number of story points, got '%s'", value)
+	}
+	return estimate, nil
+}
+
+// FormatEstimate writes an estimate without trailing zeros, e.g. 3 or 0.5.
+func FormatEstimate(estimate float64) string {
+	return strconv.FormatFloat(estimate, 'f', -1, 64)
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "testing"
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Priority
+		wantErr bool
+	}{
+		{name: "must have", want: PriorityMust},
+		{name: "S", want: PriorityShould},
+		{name: "could", want: PriorityCould},
+		{name: "wont", want: PriorityWont},
+		{name: "Won’t have", want: PriorityWont},
+		{name: " 03 ", want: "3"},
+		{name: "", want: ""},
+		{name: "0", wantErr: true},
+		{name: "urgent", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePriority(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePriority(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if rank, ok := PriorityShould.Rank(); !ok || rank != 2 {
+		t.Errorf("Should.Rank() = %d, %v, want 2", rank, ok)
+	}
+	if _, ok := Priority("").Rank(); ok {
+		t.Error("empty priority has a rank")
+	}
+}
+
+func TestParseEstimate(t *testing.T) {
+	if got, err := ParseEstimate("0.5"); err != nil || got != 0.5 || FormatEstimate(got) != "0.5" {
+		t.Errorf("ParseEstimate(\"0.5\") = %v, %v", got, err)
+	}
+	if _, err := ParseEstimate("-1"); err == nil {
+		t.Error("ParseEstimate(\"-1\") accepted a negative estimate")
+	}
+	if _, err := ParseEstimate("large"); err == nil {
+		t.Error("ParseEstimate(\"large\") accepted a non-number")
+	}
+}
@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PromptName identifies one of the system prompts sent to the LLM.
+type PromptName string
+
+const (
+	PromptCategorize         PromptName = "categorize"
+	PromptCategorizeBatch    PromptName = "categorize-batch"
+	PromptSummarize          PromptName = "summarize"
+	PromptGenerate           PromptName = "generate"
+	PromptPossibleCategories PromptName = "possible-categories"
+	PromptDuplicateCheck     PromptName = "duplicate-check"
+	PromptDuplicateGroups    PromptName = "duplicate-groups"
+	PromptCriteria           PromptName = "criteria"
+	PromptPrioritize         PromptName = "prioritize"
+)
+
+// PromptSourceBuiltIn is the source of the prompts that ship with muserstory.
+const PromptSourceBuiltIn = "built-in"
+
+// promptFileExtension is the extension of prompt template files.
+const promptFileExtension = ".tmpl"
+
+var promptDescriptions = map[PromptName]string{
+	PromptCategorize:         "Categorize a single story; .Categories lists the known categories, if any, and .Taxonomy the declared ones.",
+	PromptCategorizeBatch:    "Categorize several stories in one request; .Categories lists the known categories, and .Taxonomy the declared ones.",
+	PromptSummarize:          "Summarize the project from its stories.",
+	PromptGenerate:           "Generate new stories; .Count is the number of stories to generate.",
+	PromptPossibleCategories: "Suggest categories for the stories.",
+	PromptDuplicateCheck:     "Find existing stories that duplicate a new story.",
+	PromptDuplicateGroups:    "Group stories that duplicate each other.",
+	PromptCriteria:           "Draft acceptance criteria for a story; .Count is the number of criteria to draft, zero to let the model decide.",
+	PromptPrioritize:         "Rank stories by importance; .Priorities lists the MoSCoW priorities to assign, and is empty when the rank is the priority.",
+}
+
+//go:embed prompts/*.tmpl
+var builtInPrompts embed.FS
+
+// PromptNames returns the names of all prompts in a stable order.
+func PromptNames() []PromptName {
+	return []PromptName{
+		PromptCategorize,
+		PromptCategorizeBatch,
+		PromptSummarize,
+		PromptGenerate,
+		PromptPossibleCategories,
+		PromptDuplicateCheck,
+		PromptDuplicateGroups,
+		PromptCriteria,
+		PromptPrioritize,
+	}
+}
+
+// ParsePromptName accepts a prompt name with or without the template file extension.
+func ParsePromptName(name string) (PromptName, error) {
+	promptName := PromptName(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), promptFileExtension))
+	if _, ok := promptDescriptions[promptName]; !ok {
+		names := make([]string, 0, len(promptDescriptions))
+		for _, known := range PromptNames() {
+			names = append(names, string(known))
+		}
+		return "", fmt.Errorf("unknown prompt '%s', expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return promptName, nil
+}
+
+// FileName returns the name of the template file of the prompt, e.g. summarize.tmpl.
+func (n PromptName) FileName() string {
+	return string(n) + promptFileExtension
+}
+
+// Description explains what the prompt is used for and which fields it can use.
+func (n PromptName) Description() string {
+	return promptDescriptions[n]
+}
+
+// PromptTemplate is the text/template source of a system prompt and where it came from:
+// built-in, a file path or the story file's front matter.
+type PromptTemplate struct {
+	Name   PromptName
+	Source string
+	Text   string
+}
+
+// PromptData is what prompt templates can refer to. Metadata is the front matter of the
+// story file, so a template can use e.g. {{.Metadata.language}}.
+type PromptData struct {
+	Categories []string
+	// Taxonomy is the declared taxonomy when categorizing a file that has one, with the
+	// descriptions of its categories and the fallback.
+	Taxonomy *Taxonomy
+	Count    int
+	// Priorities are the priorities prioritize may assign; empty when stories are ranked
+	// by number.
+	Priorities []string
+	Metadata   map[string]interface{}
+}
+
+// DefaultPromptTemplates returns the built-in prompts.
+func DefaultPromptTemplates() map[PromptName]PromptTemplate {
+	templates := make(map[PromptName]PromptTemplate, len(promptDescriptions))
+	for _, name := range PromptNames() {
+		text, err := builtInPrompts.ReadFile("prompts/" + name.FileName())
+		if err != nil {
+			panic(fmt.Sprintf("built-in prompt %s is missing: %v", name, err))
+		}
+		templates[name] = PromptTemplate{Name: name, Source: PromptSourceBuiltIn, Text: string(text)}
+	}
+	return templates
+}
+
+// FrontMatterPrompts returns the prompts overridden in the `prompts` map of the front matter.
+func FrontMatterPrompts(metadata map[string]interface{}) (map[PromptName]PromptTemplate, error) {
+	raw, ok := metadata["prompts"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("front matter 'prompts' must map prompt names to templates")
+	}
+	templates := make(map[PromptName]PromptTemplate, len(entries))
+	for key, value := range entries {
+		name, err := ParsePromptName(key)
+		if err != nil {
+			return nil, fmt.Errorf("front matter prompts: %w", err)
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("front matter prompt '%s' must be a string", key)
+		}
+		templates[name] = PromptTemplate{Name: name, Source: "front matter", Text: text}
+	}
+	return templates, nil
+}
+
+// PromptSet holds the parsed template of every prompt.
+type PromptSet struct {
+	templates map[PromptName]PromptTemplate
+	parsed    map[PromptName]*template.Template
+}
+
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// NewPromptSet combines layers of prompt templates, later layers overriding earlier ones,
+// and parses them. The first layer is normally DefaultPromptTemplates.
+func NewPromptSet(layers ...map[PromptName]PromptTemplate) (*PromptSet, error) {
+	set := &PromptSet{
+		templates: make(map[PromptName]PromptTemplate),
+		parsed:    make(map[PromptName]*template.Template),
+	}
+	for _, layer := range layers {
+		for name, prompt := range layer {
+			set.templates[name] = prompt
+		}
+	}
+	for name, prompt := range set.templates {
+		parsed, err := template.New(string(name)).Funcs(promptFuncs).Parse(prompt.Text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing prompt %s from %s: %w", name, prompt.Source, err)
+		}
+		set.parsed[name] = parsed
+	}
+	return set, nil
+}
+
+// Template returns the template used for the prompt.
+func (p *PromptSet) Template(name PromptName) (PromptTemplate, bool) {
+	prompt, ok := p.templates[name]
+	return prompt, ok
+}
+
+// Templates returns the templates in the order of PromptNames.
+func (p *PromptSet) Templates() []PromptTemplate {
+	var templates []PromptTemplate
+	for _, name := range PromptNames() {
+		if prompt, ok := p.templates[name]; ok {
+			templates = append(templates, prompt)
+		}
+	}
+	return templates
+}
+
+// Render executes the prompt's template with data and trims the surrounding whitespace.
+func (p *PromptSet) Render(name PromptName, data PromptData) (string, error) {
+	parsed, ok := p.parsed[name]
+	if !ok {
+		return "", fmt.Errorf("no template for prompt %s", name)
+	}
+	var text strings.Builder
+	if err := parsed.Execute(&text, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt %s from %s: %w", name, p.templates[name].Source, err)
+	}
+	return strings.TrimSpace(text.String()), nil
+}
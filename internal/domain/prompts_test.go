@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultPrompts(t *testing.T) {
+	prompts, err := NewPromptSet(DefaultPromptTemplates())
+	if err != nil {
+		t.Fatalf("NewPromptSet() error = %v", err)
+	}
+	if got := len(prompts.Templates()); got != len(PromptNames()) {
+		t.Errorf("got %d built-in prompts, want %d", got, len(PromptNames()))
+	}
+
+	tests := []struct {
+		name PromptName
+		data PromptData
+		want string
+	}{
+		{PromptCategorize, PromptData{}, "Categorize the following user story. Only return the category name."},
+		{PromptCategorize, PromptData{Categories: []string{"Bug", "Feature"}}, "Categorize the following user story. Only return the category name. Possible categories are: Bug, Feature"},
+		{PromptGenerate, PromptData{Count: 3}, "Based on the provided context of existing user stories (if any), generate exactly 3 new"},
+	}
+	for _, tt := range tests {
+		got, err := prompts.Render(tt.name, tt.data)
+		if err != nil {
+			t.Fatalf("Render(%s) error = %v", tt.name, err)
+		}
+		if !strings.HasPrefix(got, tt.want) || (tt.name == PromptCategorize && got != tt.want) {
+			t.Errorf("Render(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPromptOverrides(t *testing.T) {
+	frontMatter, err := FrontMatterPrompts(map[string]interface{}{
+		"prompts": map[string]interface{}{"Summarize": "In {{.Metadata.language}}, please."},
+	})
+	if err != nil {
+		t.Fatalf("FrontMatterPrompts() error = %v", err)
+	}
+	dir := map[PromptName]PromptTemplate{
+		PromptSummarize: {Name: PromptSummarize, Source: "prompts/summarize.tmpl", Text: "Ignored."},
+	}
+	prompts, err := NewPromptSet(DefaultPromptTemplates(), dir, frontMatter)
+	if err != nil {
+		t.Fatalf("NewPromptSet() error = %v", err)
+	}
+	got, err := prompts.Render(PromptSummarize, PromptData{Metadata: map[string]interface{}{"language": "Swedish"}})
+	if err != nil || got != "In Swedish, please." {
+		t.Errorf("Render() = %q, %v", got, err)
+	}
+
+	if _, err := FrontMatterPrompts(map[string]interface{}{"prompts": map[string]interface{}{"summary": "x"}}); err == nil {
+		t.Error("FrontMatterPrompts() accepted an unknown prompt name")
+	}
+	broken := map[PromptName]PromptTemplate{PromptGenerate: {Name: PromptGenerate, Source: "front matter", Text: "{{.Count"}}
+	if _, err := NewPromptSet(DefaultPromptTemplates(), broken); err == nil || !strings.Contains(err.Error(), "front matter") {
+		t.Errorf("NewPromptSet() with a broken template error = %v, want one naming the source", err)
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "strings"
+
+// similarityStopWords are ignored when comparing descriptions, so the shared
+// "As a user, I want to ... so that ..." template does not make every story look alike.
+var similarityStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "as": true, "i": true, "want": true, "to": true,
+	"so": true, "that": true, "can": true, "be": true, "able": true, "user": true,
+	"my": true, "me": true, "of": true, "and": true, "or": true, "in": true, "for": true,
+	"with": true, "on": true, "is": true, "it": true, "are": true, "by": true, "from": true,
+}
+
+// DescriptionSimilarity scores how alike two story descriptions are, from 0 (nothing in
+// common) to 1 (the same words). It is the Jaccard index of the significant words.
+func DescriptionSimilarity(a, b string) float64 {
+	wordsA := significantWords(a)
+	wordsB := significantWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		if NormalizeDescription(a) == NormalizeDescription(b) {
+			return 1
+		}
+		return 0
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func significantWords(description string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(NormalizeDescription(description)) {
+		if !similarityStopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "testing"
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		atLeast float64
+		below   float64
+	}{
+		{name: "identical", a: "As a user, I want to reset my password", b: "as a user i want to reset my password!", atLeast: 1, below: 1.01},
+		{name: "reworded", a: "As a user, I want to reset my password so that I can log in again", b: "As a customer, I want to reset my password so I can log in", atLeast: 0.6, below: 1},
+		{name: "template only in common", a: "As a user, I want to export reports", b: "As a user, I want dark mode", atLeast: 0, below: 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DescriptionSimilarity(tt.a, tt.b)
+			if got < tt.atLeast || got >= tt.below {
+				t.Errorf("DescriptionSimilarity() = %v, want in [%v, %v)", got, tt.atLeast, tt.below)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusProposed   Status = "Proposed"
+	StatusAccepted   Status = "Accepted"
+	StatusInProgress Status = "In Progress"
+	StatusDone       Status = "Done"
+	StatusDeclined   Status = "Declined"
+)
+
+// Workflow describes the statuses a story can have and which moves between them are allowed.
+// A nil Transitions map allows moving between any two states.
+type Workflow struct {
+	States      []Status
+	Transitions map[Status][]Status
+}
+
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		States: []Status{StatusProposed, StatusAccepted, StatusInProgress, StatusDone, StatusDeclined},
+		Transitions: map[Status][]Status{
+			StatusProposed:   {StatusAccepted, StatusDeclined},
+			StatusAccepted:   {StatusInProgress, StatusDeclined, StatusProposed},
+			StatusInProgress: {StatusDone, StatusAccepted},
+			StatusDone:       {StatusInProgress},
+			StatusDeclined:   {StatusProposed},
+		},
+	}
+}
+
+// WorkflowFromMetadata reads an optional "workflow" section from the file metadata:
+//
+//	workflow:
+//	  states: [Todo, Doing, Done]
+//	  transitions:
+//	    Todo: [Doing]
+//	    Doing: [Todo, Done]
+//
+// The default workflow is returned when the section is missing.
+func WorkflowFromMetadata(metadata map[string]interface{}) (Workflow, error) {
+	raw, ok := metadata["workflow"]
+	if !ok || raw == nil {
+		return DefaultWorkflow(), nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return Workflow{}, fmt.Errorf("workflow metadata must be a mapping")
+	}
+
+	stateNames, err := toStringList(section["states"])
+	if err != nil {
+		return Workflow{}, fmt.Errorf("invalid workflow states: %w", err)
+	}
+	if len(stateNames) == 0 {
+		return Workflow{}, fmt.Errorf("workflow metadata must list at least one state")
+	}
+
+	workflow := Workflow{}
+	for _, name := range stateNames {
+		workflow.States = append(workflow.States, Status(strings.TrimSpace(name)))
+	}
+
+	rawTransitions, ok := section["transitions"]
+	if !ok || rawTransitions == nil {
+		return workflow, nil
+	}
+	transitions, ok := rawTransitions.(map[string]interface{})
+	if !ok {
+		return Workflow{}, fmt.Errorf("workflow transitions must be a mapping")
+	}
+	workflow.Transitions = make(map[Status][]Status)
+	for from, rawTargets := range transitions {
+		fromStatus, ok := workflow.Resolve(from)
+		if !ok {
+			return Workflow{}, fmt.Errorf("workflow transition from unknown state '%s'", from)
+		}
+		targets, err := toStringList(rawTargets)
+		if err != nil {
+			return Workflow{}, fmt.Errorf("invalid transitions for state '%s': %w", from, err)
+		}
+		for _, target := range targets {
+			toStatus, ok := workflow.Resolve(target)
+			if !ok {
+				return Workflow{}, fmt.Errorf("workflow transition from '%s' to unknown state '%s'", from, target)
+			}
+			workflow.Transitions[fromStatus] = append(workflow.Transitions[fromStatus], toStatus)
+		}
+	}
+	return workflow, nil
+}
+
+// Resolve finds the workflow state matching name, ignoring case and treating
+// spaces, dashes and underscores alike, so "in-progress" resolves to "In Progress".
+func (w Workflow) Resolve(name string) (Status, bool) {
+	wanted := normalizeStatusName(name)
+	for _, state := range w.States {
+		if normalizeStatusName(string(state)) == wanted {
+			return state, true
+		}
+	}
+	return "", false
+}
+
+// CanTransition reports whether a story may move from one status to another.
+// Stories without a status may move to any state.
+func (w Workflow) CanTransition(from, to Status) bool {
+	if from == "" || from == to || w.Transitions == nil {
+		return true
+	}
+	for _, allowed := range w.Transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether name refers to this status, using the same loose matching as Resolve.
+func (s Status) Matches(name string) bool {
+	return normalizeStatusName(string(s)) == normalizeStatusName(name)
+}
+
+func normalizeStatusName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(name)
+}
+
+func toStringList(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list")
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "testing"
+
+func TestWorkflowFromMetadata(t *testing.T) {
+	metadata := map[string]interface{}{
+		"workflow": map[string]interface{}{
+			"states": []interface{}{"Todo", "In Review", "Done"},
+			"transitions": map[string]interface{}{
+				"Todo":      []interface{}{"In Review"},
+				"In Review": []interface{}{"Todo", "Done"},
+			},
+		},
+	}
+
+	workflow, err := WorkflowFromMetadata(metadata)
+	if err != nil {
+		t.Fatalf("WorkflowFromMetadata() error = %v", err)
+	}
+	if len(workflow.States) != 3 {
+		t.Fatalf("WorkflowFromMetadata() states = %v, want 3", workflow.States)
+	}
+
+	review, ok := workflow.Resolve("in-review")
+	if !ok || review != "In Review" {
+		t.Errorf("Resolve(\"in-review\") = %q, %v", review, ok)
+	}
+	if _, ok := workflow.Resolve("Accepted"); ok {
+		t.Errorf("Resolve(\"Accepted\") should fail for a custom workflow")
+	}
+	if !workflow.CanTransition("Todo", "In Review") {
+		t.Errorf("CanTransition(Todo, In Review) = false, want true")
+	}
+	if workflow.CanTransition("Todo", "Done") {
+		t.Errorf("CanTransition(Todo, Done) = true, want false")
+	}
+	if !workflow.CanTransition("", "Done") {
+		t.Errorf("CanTransition(\"\", Done) = false, want true")
+	}
+}
+
+func TestWorkflowFromMetadataDefault(t *testing.T) {
+	workflow, err := WorkflowFromMetadata(nil)
+	if err != nil {
+		t.Fatalf("WorkflowFromMetadata(nil) error = %v", err)
+	}
+	if status, ok := workflow.Resolve("in progress"); !ok || status != StatusInProgress {
+		t.Errorf("Resolve(\"in progress\") = %q, %v", status, ok)
+	}
+	if workflow.CanTransition(StatusProposed, StatusDone) {
+		t.Errorf("default workflow should not allow Proposed -> Done")
+	}
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultFallbackCategory is assigned when an answer is not part of the taxonomy.
+const DefaultFallbackCategory = "Uncategorized"
+
+// CategoryDefinition is one category of a taxonomy, with an optional description that
+// tells the LLM what belongs in it.
+type CategoryDefinition struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// Taxonomy is the fixed list of categories declared in the story file's front matter:
+//
+//	categories:
+//	  - Authentication
+//	  - name: Billing
+//	    description: Payments, invoices and plans
+//	category_fallback: Other
+//
+// Categorization only assigns these categories, and the fallback for anything else.
+type Taxonomy struct {
+	Categories []CategoryDefinition
+	Fallback   string
+}
+
+// ParseTaxonomy reads the taxonomy from front matter. It returns nil when the front matter
+// declares no categories.
+func ParseTaxonomy(metadata map[string]interface{}) (*Taxonomy, error) {
+	raw, ok := metadata["categories"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("front matter 'categories' must be a list")
+	}
+
+	taxonomy := &Taxonomy{Fallback: DefaultFallbackCategory}
+	if fallback, ok := metadata["category_fallback"].(string); ok && strings.TrimSpace(fallback) != "" {
+		taxonomy.Fallback = strings.TrimSpace(fallback)
+	}
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		var category CategoryDefinition
+		switch value := entry.(type) {
+		case string:
+			category.Name = value
+		case map[string]interface{}:
+			category.Name, _ = value["name"].(string)
+			category.Description, _ = value["description"].(string)
+		default:
+			return nil, fmt.Errorf("front matter category %d must be a name or have a name and description", i+1)
+		}
+		category.Name = strings.TrimSpace(category.Name)
+		category.Description = strings.TrimSpace(category.Description)
+		if category.Name == "" {
+			return nil, fmt.Errorf("front matter category %d has no name", i+1)
+		}
+		if seen[strings.ToLower(category.Name)] {
+			return nil, fmt.Errorf("front matter category '%s' is declared twice", category.Name)
+		}
+		seen[strings.ToLower(category.Name)] = true
+		taxonomy.Categories = append(taxonomy.Categories, category)
+	}
+	if len(taxonomy.Categories) == 0 {
+		return nil, nil
+	}
+	return taxonomy, nil
+}
+
+// Names returns the category names in declaration order.
+func (t *Taxonomy) Names() []string {
+	names := make([]string, len(t.Categories))
+	for i, category := range t.Categories {
+		names[i] = category.Name
+	}
+	return names
+}
+
+// allowed returns the categories an answer may name: the taxonomy and its fallback.
+func (t *Taxonomy) allowed() []string {
+	names := t.Names()
+	for _, name := range names {
+		if strings.EqualFold(name, t.Fallback) {
+			return names
+		}
+	}
+	return append(names, t.Fallback)
+}
+
+// Resolve maps an LLM answer to the taxonomy, ignoring case and surrounding whitespace.
+// Answers outside the taxonomy resolve to the fallback and false.
+func (t *Taxonomy) Resolve(answer string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+	for _, name := range t.allowed() {
+		if strings.EqualFold(name, answer) {
+			return name, true
+		}
+	}
+	return t.Fallback, false
+}
+
+// ConstrainSchema restricts every "category" property of a schema made by GenerateSchema
+// to the taxonomy and its fallback, and returns the schema.
+func (t *Taxonomy) ConstrainSchema(schema interface{}) interface{} {
+	return ConstrainEnum(schema, "category", t.allowed())
+}
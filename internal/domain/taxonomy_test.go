@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseTaxonomy(t *testing.T) {
+	var metadata map[string]interface{}
+	content := `
+categories:
+  - Authentication
+  - name: Billing
+    description: Payments and invoices
+category_fallback: Other
+`
+	if err := yaml.Unmarshal([]byte(content), &metadata); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	taxonomy, err := ParseTaxonomy(metadata)
+	if err != nil {
+		t.Fatalf("ParseTaxonomy() error = %v", err)
+	}
+	if strings.Join(taxonomy.Names(), ",") != "Authentication,Billing" || taxonomy.Fallback != "Other" ||
+		taxonomy.Categories[1].Description != "Payments and invoices" {
+		t.Errorf("ParseTaxonomy() = %+v", taxonomy)
+	}
+
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{"Billing", "Billing", true},
+		{" authentication ", "Authentication", true},
+		{"other", "Other", true},
+		{"Auth", "Other", false},
+	}
+	for _, tt := range tests {
+		if got, ok := taxonomy.Resolve(tt.answer); got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", tt.answer, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if taxonomy, err := ParseTaxonomy(map[string]interface{}{"title": "No taxonomy"}); err != nil || taxonomy != nil {
+		t.Errorf("ParseTaxonomy() without categories = %+v, %v", taxonomy, err)
+	}
+	if _, err := ParseTaxonomy(map[string]interface{}{"categories": []interface{}{"Bug", "bug"}}); err == nil {
+		t.Error("ParseTaxonomy() accepted a category declared twice")
+	}
+}
+
+func TestTaxonomyConstrainSchema(t *testing.T) {
+	type item struct {
+		ID       string `json:"id"`
+		Category string `json:"category"`
+	}
+	type response struct {
+		Categories []item `json:"categories"`
+	}
+	taxonomy := &Taxonomy{Categories: []CategoryDefinition{{Name: "Bug"}, {Name: "Feature"}}, Fallback: DefaultFallbackCategory}
+	data, err := json.Marshal(taxonomy.ConstrainSchema(GenerateSchema[response]()))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"enum":["Bug","Feature","Uncategorized"]`) {
+		t.Errorf("schema %s does not restrict the category", data)
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelPrice is the price of a model in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// PriceTable maps model names to their price. A model also matches the longest entry it
+// starts with, so dated snapshots like gpt-4o-mini-2024-07-18 use the gpt-4o-mini price.
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable holds list prices of the default models at the time of writing.
+// Configure `pricing` to keep them current or to add other models.
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+		"gpt-4o":            {Input: 2.50, Output: 10.00},
+		"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+		"gpt-4.1":           {Input: 2.00, Output: 8.00},
+		"o3-mini":           {Input: 1.10, Output: 4.40},
+		"o1":                {Input: 15.00, Output: 60.00},
+		"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+		"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+		"claude-opus-4":     {Input: 15.00, Output: 75.00},
+		"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+		"offline":           {},
+	}
+}
+
+// Merge returns a copy of p with the prices of overrides added or replaced.
+func (p PriceTable) Merge(overrides PriceTable) PriceTable {
+	merged := make(PriceTable, len(p)+len(overrides))
+	for model, price := range p {
+		merged[model] = price
+	}
+	for model, price := range overrides {
+		merged[model] = price
+	}
+	return merged
+}
+
+// Cost returns the price of the given tokens for model, and false when the model has no price.
+func (p PriceTable) Cost(model string, promptTokens int64, completionTokens int64) (float64, bool) {
+	price, ok := p[model]
+	if !ok {
+		longest := ""
+		for name, candidate := range p {
+			if strings.HasPrefix(model, name) && len(name) > len(longest) {
+				longest, price, ok = name, candidate, true
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6, true
+}
+
+// ModelUsage is the usage of one model added up over several requests.
+type ModelUsage struct {
+	Model            string
+	Requests         int
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// UsageRecord is one line of the usage log: what a command used of one model.
+type UsageRecord struct {
+	Time             time.Time `json:"time"`
+	Command          string    `json:"command"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Requests         int       `json:"requests"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	// Cost is in US dollars; it is zero when the model had no price.
+	Cost float64 `json:"cost"`
+}
+
+// NewUsageRecords prices the usage of a command, one record per model.
+func NewUsageRecords(usage []ModelUsage, prices PriceTable, command string, provider string, at time.Time) []UsageRecord {
+	records := make([]UsageRecord, 0, len(usage))
+	for _, modelUsage := range usage {
+		cost, _ := prices.Cost(modelUsage.Model, modelUsage.PromptTokens, modelUsage.CompletionTokens)
+		records = append(records, UsageRecord{
+			Time:             at,
+			Command:          command,
+			Provider:         provider,
+			Model:            modelUsage.Model,
+			Requests:         modelUsage.Requests,
+			PromptTokens:     modelUsage.PromptTokens,
+			CompletionTokens: modelUsage.CompletionTokens,
+			Cost:             cost,
+		})
+	}
+	return records
+}
+
+// UsageReportRow adds up the usage of one command on one day.
+type UsageReportRow struct {
+	Day              string  `json:"day"`
+	Command          string  `json:"command"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// SummarizeUsage groups records from since onwards by local day and command, ordered by
+// day and then command.
+func SummarizeUsage(records []UsageRecord, since time.Time) []UsageReportRow {
+	type key struct{ day, command string }
+	rows := make(map[key]*UsageReportRow)
+	for _, record := range records {
+		if record.Time.Before(since) {
+			continue
+		}
+		k := key{day: record.Time.Local().Format("2006-01-02"), command: record.Command}
+		row, ok := rows[k]
+		if !ok {
+			row = &UsageReportRow{Day: k.day, Command: k.command}
+			rows[k] = row
+		}
+		row.Requests += record.Requests
+		row.PromptTokens += record.PromptTokens
+		row.CompletionTokens += record.CompletionTokens
+		row.Cost += record.Cost
+	}
+
+	report := make([]UsageReportRow, 0, len(rows))
+	for _, row := range rows {
+		report = append(report, *row)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Day != report[j].Day {
+			return report[i].Day < report[j].Day
+		}
+		return report[i].Command < report[j].Command
+	})
+	return report
+}
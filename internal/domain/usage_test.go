@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPriceTableCost(t *testing.T) {
+	prices := DefaultPriceTable().Merge(PriceTable{"gpt-4o": {Input: 5, Output: 20}})
+	tests := []struct {
+		model string
+		want  float64
+		ok    bool
+	}{
+		{"gpt-4o", 5 + 20, true},
+		// Dated snapshots use the longest matching prefix, not gpt-4o.
+		{"gpt-4o-mini-2024-07-18", 0.15 + 0.60, true},
+		{"llama3.1", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := prices.Cost(tt.model, 1_000_000, 1_000_000)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cost(%q) = %v, %v, want %v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	records := []UsageRecord{
+		{Time: day.AddDate(0, 0, -5), Command: "categorize", Requests: 9, Cost: 9},
+		{Time: day, Command: "summarize", Requests: 1, PromptTokens: 100, Cost: 0.5},
+		{Time: day, Command: "categorize", Requests: 2, PromptTokens: 10, CompletionTokens: 2, Cost: 0.25},
+		{Time: day.Add(time.Hour), Command: "categorize", Requests: 3, PromptTokens: 20, CompletionTokens: 4, Cost: 0.25},
+	}
+
+	rows := SummarizeUsage(records, day.AddDate(0, 0, -1))
+	want := []UsageReportRow{
+		{Day: "2025-03-10", Command: "categorize", Requests: 5, PromptTokens: 30, CompletionTokens: 6, Cost: 0.5},
+		{Day: "2025-03-10", Command: "summarize", Requests: 1, PromptTokens: 100, Cost: 0.5},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("SummarizeUsage() = %+v, want %+v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
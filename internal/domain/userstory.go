@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type Project struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name"`
@@ -8,7 +10,17 @@ type Project struct {
 }
 
 type UserStory struct {
-	ID          string `json:"id"`
-	Description string `json:"description"`
-	Category    string `json:"category"`
+	ID          string   `json:"id" yaml:"id"`
+	Description string   `json:"description" yaml:"description"`
+	Category    string   `json:"category" yaml:"category"`
+	Status      Status   `json:"status" yaml:"status"`
+	Priority    Priority `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// Estimate is in story points; zero means the story has not been estimated.
+	Estimate float64 `json:"estimate,omitempty" yaml:"estimate,omitempty"`
+	// DecidedBy and DecidedAt record who accepted or declined a proposed story, and when.
+	DecidedBy string     `json:"decided_by,omitempty" yaml:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty" yaml:"decided_at,omitempty"`
+	// AcceptanceCriteria are written as indented sub-bullets below the story. A
+	// Given/When/Then scenario is a single criterion with one step per line.
+	AcceptanceCriteria []string `json:"acceptance_criteria,omitempty" yaml:"acceptance_criteria,omitempty"`
 }
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/morgansundqvist/muserstory/internal/application"
 	"github.com/morgansundqvist/muserstory/internal/domain"
 	"github.com/morgansundqvist/muserstory/internal/ports"
 )
@@ -66,3 +69,67 @@ func (h *ProjectHandler) GetProjectByID(c *fiber.Ctx) error {
 	}
 	return c.JSON(project)
 }
+
+// GetProjectStories returns the user stories of a project filtered by the query parameters
+// category, status, id (comma-separated), text, regex, sort and desc.
+func (h *ProjectHandler) GetProjectStories(c *fiber.Ctx) error {
+	id := c.Params("id")
+	project, err := h.Repo.GetProjectByID(id)
+	if err != nil {
+		if err.Error() == "project with ID '"+id+"' not found" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":   "project not found",
+				"details": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "failed to get project by ID",
+			"details": err.Error(),
+		})
+	}
+
+	query, err := storyQueryFromRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid query",
+			"details": err.Error(),
+		})
+	}
+	stories, err := query.Apply(project.UserStories)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "invalid query",
+			"details": err.Error(),
+		})
+	}
+	return c.JSON(stories)
+}
+
+func storyQueryFromRequest(c *fiber.Ctx) (application.StoryQuery, error) {
+	sortBy, err := application.ParseSortField(c.Query("sort"))
+	if err != nil {
+		return application.StoryQuery{}, err
+	}
+	return application.StoryQuery{
+		Categories:  splitQueryList(c.Query("category")),
+		Statuses:    splitQueryList(c.Query("status")),
+		IDs:         splitQueryList(c.Query("id")),
+		Text:        c.Query("text"),
+		TextIsRegex: c.QueryBool("regex", false),
+		SortBy:      sortBy,
+		Descending:  c.QueryBool("desc", false),
+	}, nil
+}
+
+func splitQueryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInputRequired is returned by Ask when nobody is there to answer, e.g. in scripts, CI
+// jobs or the HTTP server.
+var ErrInputRequired = errors.New("input required in non-interactive mode")
+
+// Interaction is how the application talks to whoever runs it while a command is in progress.
+type Interaction interface {
+	// Notify reports progress, a warning or a preview to be confirmed. It may be called
+	// from several goroutines at once.
+	Notify(message string)
+	// Ask shows question and returns the trimmed answer. It returns the context's error
+	// when the context is cancelled while waiting, and ErrInputRequired when nobody can answer.
+	Ask(ctx context.Context, question string) (string, error)
+}
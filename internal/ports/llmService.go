@@ -1,9 +1,19 @@
 package ports
 
-import "github.com/morgansundqvist/muserstory/internal/domain"
+import (
+	"context"
+
+	"github.com/morgansundqvist/muserstory/internal/domain"
+)
 
 type LLMService interface {
-	AskSimple(input domain.LLMSimpleInput) (string, error)
+	AskSimple(ctx context.Context, input domain.LLMSimpleInput) (domain.LLMResponse, error)
+
+	AskAdvanced(ctx context.Context, input domain.LLMAdvancedInput) (domain.LLMResponse, error)
+}
 
-	AskAdvanced(input domain.LLMAdvancedInput) (string, error)
+// ModelNamer is implemented by LLM services that know which concrete model answers requests
+// of a model type. Services that wrap another one pass the question on.
+type ModelNamer interface {
+	ModelName(modelType domain.ModelType) string
 }